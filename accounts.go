@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/some-programs/battlr/assets"
+	"github.com/some-programs/battlr/pkg/db"
+)
+
+var accountTmpl = template.Must(template.New("base.html").
+	Funcs(template.FuncMap{
+		"static": assets.StaticHashFS.HashName,
+	}).
+	ParseFS(assets.TemplateFS, "template/base.html", "template/account.html"))
+
+// safeNext returns the local path to continue to after logging in.
+func safeNext(next string) string {
+	u, err := url.Parse(next)
+	if err != nil || next == "" || u.IsAbs() || u.Host != "" || !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") {
+		return "/battles/"
+	}
+	return next
+}
+
+// accountURL returns the account page url which continues to the path of r.
+func accountURL(r *http.Request) string {
+	return "/account/?next=" + url.QueryEscape(r.URL.Path)
+}
+
+func (s *Server) renderAccount(w http.ResponseWriter, r *http.Request, status int, errMsg string, next string) error {
+	templateData := struct {
+		Title string
+		User  *db.User
+		Next  string
+		Error string
+	}{
+		Title: "Account",
+		User:  getUser(r.Context()),
+		Next:  safeNext(next),
+		Error: errMsg,
+	}
+	w.WriteHeader(status)
+	if err := accountTmpl.Execute(w, &templateData); err != nil {
+		slog.Info("error", "err", err)
+		return err
+	}
+	return nil
+}
+
+func (s *Server) AccountPage() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		return s.renderAccount(w, r, http.StatusOK, "", r.URL.Query().Get("next"))
+	}
+}
+
+// startSession logs the user in and moves the votes of the browser cookie to
+// the account.
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, user db.User) error {
+	if err := s.DB.LinkVoter(user, getCookieClientID(r.Context())); err != nil {
+		return err
+	}
+	token, err := s.DB.CreateSession(user)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Expires:  time.Now().Add(db.SessionDuration),
+		Path:     "/",
+		SameSite: http.SameSiteLaxMode,
+		HttpOnly: true,
+	})
+	http.Redirect(w, r, safeNext(r.PostFormValue("next")), http.StatusSeeOther)
+	return nil
+}
+
+func (s *Server) Login() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		next := r.PostFormValue("next")
+		user, err := s.DB.Authenticate(r.PostFormValue("username"), r.PostFormValue("password"))
+		if errors.Is(err, db.InvalidLogin) {
+			return s.renderAccount(w, r, http.StatusUnauthorized, err.Error(), next)
+		}
+		if err != nil {
+			return err
+		}
+		return s.startSession(w, r, *user)
+	}
+}
+
+func (s *Server) Register() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		next := r.PostFormValue("next")
+		user, err := s.DB.CreateUser(r.PostFormValue("username"), r.PostFormValue("password"))
+		switch {
+		case errors.Is(err, db.InvalidAccount):
+			return s.renderAccount(w, r, http.StatusBadRequest, err.Error(), next)
+		case errors.Is(err, db.AccountExists):
+			return s.renderAccount(w, r, http.StatusConflict, err.Error(), next)
+		case err != nil:
+			return err
+		}
+		return s.startSession(w, r, *user)
+	}
+}
+
+func (s *Server) Logout() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if c, err := r.Cookie(sessionCookieName); err == nil {
+			if err := s.DB.DeleteSession(c.Value); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookieName,
+			Path:     "/",
+			MaxAge:   -1,
+			SameSite: http.SameSiteLaxMode,
+			HttpOnly: true,
+		})
+		http.Redirect(w, r, "/account/", http.StatusSeeOther)
+		return nil
+	}
+}
+
+var errAccountRequired = errors.New("log in to vote")
+
+// accountRequired reports whether voting needs a login the client does not
+// have.
+func (s *Server) accountRequired(r *http.Request) bool {
+	return s.RequireAccount && getUser(r.Context()) == nil
+}
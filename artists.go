@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+
+	"github.com/some-programs/battlr/assets"
+	"github.com/some-programs/battlr/pkg/db"
+)
+
+func (s *Server) ArtistPage() AppHandler {
+	tmpl, err := template.New("base.html").
+		Funcs(template.FuncMap{
+			"static": assets.StaticHashFS.HashName,
+			"round": func(f float64) int {
+				return int(math.Round(f))
+			},
+		},
+		).
+		ParseFS(assets.TemplateFS, "template/base.html", "template/artist.html")
+	if err != nil {
+		slog.Error("failed to parse clients template",
+			"err", err,
+		)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		artist, err := s.DB.GetArtist(r.PathValue("name"))
+		if err != nil {
+			return err
+		}
+		if artist == nil {
+			return s.ErrorPage(r.Context(), w, r, "no battle results found for the artist", "/battles/")
+		}
+
+		ratings, err := s.DB.ArtistRatings()
+		if err != nil {
+			return err
+		}
+		history := ratings[artist.Key]
+		slices.Reverse(history)
+
+		templateData := struct {
+			Title         string
+			Artist        db.Artist
+			Config        ServerConfig
+			PublicPlaces  int
+			RatingHistory []db.RatingPoint
+		}{
+			Title:         artist.Name,
+			Artist:        *artist,
+			Config:        s.ServerConfig,
+			PublicPlaces:  s.publicPlaces(),
+			RatingHistory: history,
+		}
+
+		w.WriteHeader(http.StatusOK)
+
+		if err := tmpl.Execute(w, &templateData); err != nil {
+			slog.Info("error", "err", err)
+			return err
+		}
+		return nil
+	}
+}
+
+func (s *Server) GetAliases() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		aliases, err := s.DB.GetAllAliases()
+		if err != nil {
+			return err
+		}
+		WriteJSONResponse(r.Context(), w, http.StatusOK, aliases)
+		return nil
+	}
+}
+
+// AliasRequest makes the alias in the path a spelling of Artist.
+type AliasRequest struct {
+	Artist string `json:"artist"`
+}
+
+func (s *Server) PutAlias() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		var req AliasRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			WriteJSONResponse(r.Context(), w, http.StatusBadRequest, inspectError(err))
+			return nil
+		}
+		if err := s.DB.PutAlias(r.PathValue("alias"), req.Artist); err != nil {
+			if errors.Is(err, db.InvalidAlias) {
+				WriteJSONResponse(r.Context(), w, http.StatusBadRequest, inspectError(err))
+				return nil
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+func (s *Server) DeleteAlias() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		err := s.DB.DeleteAlias(r.PathValue("alias"))
+		if errors.Is(err, db.NotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		return err
+	}
+}
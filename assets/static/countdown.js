@@ -0,0 +1,19 @@
+"use strict";
+
+const formatDuration = (ms) => {
+  const total = Math.floor(ms / 1000);
+  const days = Math.floor(total / 86400);
+  const pad = (n) => String(n).padStart(2, "0");
+  const hms = `${pad(Math.floor((total % 86400) / 3600))}:${pad(Math.floor((total % 3600) / 60))}:${pad(total % 60)}`;
+  return days > 0 ? `${days}d ${hms}` : hms;
+};
+
+const updateCountdowns = () => {
+  for (const el of document.querySelectorAll(".countdown")) {
+    const left = Date.parse(el.attributes.until.value) - Date.now();
+    el.textContent = left > 0 ? `in ${formatDuration(left)}` : "now";
+  }
+};
+
+updateCountdowns();
+setInterval(updateCountdowns, 1000);
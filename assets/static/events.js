@@ -0,0 +1,75 @@
+"use strict";
+
+// Follows the events of the battle named by the battle attribute of the
+// #battle-events element.
+(() => {
+  const root = document.getElementById("battle-events");
+  if (!root) {
+    return;
+  }
+  const battle = root.attributes.battle.value;
+  const resultsURL = `/battles/results/${encodeURIComponent(battle)}/`;
+  const scheme = window.location.protocol === "https:" ? "wss:" : "ws:";
+  const url = `${scheme}//${window.location.host}/events/${encodeURIComponent(battle)}/`;
+
+  // refreshResults replaces the #results element with a freshly rendered
+  // one, it is only on results pages.
+  const refreshResults = async () => {
+    const el = document.getElementById("results");
+    if (!el) {
+      return;
+    }
+    const res = await fetch(window.location.href);
+    if (!res.ok) {
+      return;
+    }
+    const doc = new DOMParser().parseFromString(await res.text(), "text/html");
+    const fresh = doc.getElementById("results");
+    if (fresh) {
+      el.replaceWith(fresh);
+      setupPlayers(fresh);
+    }
+  };
+
+  let reloadTimer;
+  const onEvent = (ev) => {
+    switch (ev.type) {
+      case "voter_count":
+        for (const el of document.querySelectorAll(".voter-count")) {
+          el.textContent = ev.voters;
+        }
+        root.classList.remove("hidden");
+        break;
+      case "battle_opened":
+        window.location.reload();
+        break;
+      case "battle_closed":
+        // closing is usually followed by publishing the results
+        reloadTimer = setTimeout(() => window.location.reload(), 1000);
+        break;
+      case "results_published":
+        clearTimeout(reloadTimer);
+        window.location.assign(resultsURL);
+        break;
+      case "revealed":
+        refreshResults();
+        break;
+    }
+  };
+
+  let retryDelay = 1000;
+  const connect = () => {
+    const ws = new WebSocket(url);
+    ws.addEventListener("open", () => {
+      retryDelay = 1000;
+    });
+    ws.addEventListener("message", (msg) => {
+      onEvent(JSON.parse(msg.data));
+    });
+    ws.addEventListener("close", () => {
+      setTimeout(connect, retryDelay);
+      retryDelay = Math.min(retryDelay * 2, 60000);
+    });
+  };
+  connect();
+})();
@@ -74,7 +74,21 @@ const onEnded = (event) => {
   }
 };
 
-Array.from(document.querySelectorAll("audio")).map((el) => {
-  el.addEventListener("play", onPlay);
-  el.addEventListener("ended", onEnded);
-});
+// onListen reports the first play of an entry for the ballot review.
+const onListen = (event) => {
+  const url = event.srcElement.attributes.listen;
+  if (url) {
+    fetch(url.value, { method: "POST" });
+  }
+};
+
+// setupPlayers adds the player listeners to the audio elements in root.
+const setupPlayers = (root) => {
+  Array.from(root.querySelectorAll("audio")).map((el) => {
+    el.addEventListener("play", onPlay);
+    el.addEventListener("play", onListen, { once: true });
+    el.addEventListener("ended", onEnded);
+  });
+};
+
+setupPlayers(document);
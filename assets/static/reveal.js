@@ -0,0 +1,115 @@
+"use strict";
+
+(() => {
+  const root = document.getElementById("reveal");
+  const battle = root.attributes.battle.value;
+  const apiURL = `/api/reveal/${encodeURIComponent(battle)}/`;
+  const keyName = "battlr-api-key";
+  const login = document.getElementById("reveal-login");
+  const controls = document.getElementById("reveal-controls");
+  const status = document.getElementById("reveal-status");
+  const placesEl = document.getElementById("reveal-places");
+
+  const request = async (url, method) => {
+    const res = await fetch(url, {
+      method: method,
+      headers: { Authorization: `Bearer ${localStorage.getItem(keyName)}` },
+    });
+    if (res.status === 401 || res.status === 403) {
+      localStorage.removeItem(keyName);
+      showLogin();
+      throw new Error("invalid api key");
+    }
+    const data = await res.json();
+    if (!res.ok) {
+      throw new Error(data[0].err);
+    }
+    return data;
+  };
+
+  const el = (tag, text) => {
+    const e = document.createElement(tag);
+    if (text !== undefined) {
+      e.textContent = text;
+    }
+    return e;
+  };
+
+  // render lists the places from the last to the first, the next place to
+  // reveal is highlighted and has a player.
+  const render = (state) => {
+    const { reveal, hidden, places } = state;
+    if (!reveal.active) {
+      status.textContent = "No reveal in progress, all results are published.";
+    } else if (hidden > 0) {
+      status.textContent = `${hidden} places to reveal.`;
+    } else {
+      status.textContent = "All places revealed.";
+    }
+    placesEl.replaceChildren();
+    for (const place of places.slice().reverse()) {
+      const isRevealed = !reveal.active || place.place > hidden;
+      const isNext = reveal.active && place.place === hidden;
+      const div = el("div");
+      div.classList.add("entry");
+      if (isNext) {
+        div.classList.add("entry-playing");
+      }
+      div.append(
+        el(
+          "h2",
+          `Place #${place.place} (score: ${place.score}) ${isRevealed ? "revealed" : ""}`,
+        ),
+      );
+      for (const entry of place.entries) {
+        div.append(el("h3", `${entry.author} — ${entry.title}`));
+        if (isNext) {
+          const audio = el("audio");
+          audio.controls = true;
+          audio.preload = "auto";
+          audio.src = `/dl/${encodeURIComponent(battle)}/${encodeURIComponent(entry.id)}`;
+          div.append(audio);
+        }
+      }
+      placesEl.append(div);
+    }
+  };
+
+  const load = async () => {
+    try {
+      render(await request(apiURL, "GET"));
+      controls.classList.remove("hidden");
+    } catch (e) {
+      status.textContent = `${e}`;
+    }
+  };
+
+  const showLogin = () => {
+    login.classList.remove("hidden");
+    controls.classList.add("hidden");
+  };
+
+  login.addEventListener("submit", (event) => {
+    event.preventDefault();
+    localStorage.setItem(keyName, login.elements.key.value);
+    login.classList.add("hidden");
+    load();
+  });
+
+  for (const button of controls.querySelectorAll("button")) {
+    button.addEventListener("click", async (event) => {
+      const action = event.currentTarget.attributes.action.value;
+      try {
+        render(await request(`${apiURL}${action}/`, "POST"));
+      } catch (e) {
+        status.textContent = `${e}`;
+      }
+    });
+  }
+
+  if (localStorage.getItem(keyName)) {
+    load();
+  } else {
+    showLogin();
+  }
+})();
@@ -0,0 +1,35 @@
+"use strict";
+
+const uploadStatus = document.getElementById("upload-status");
+
+const onUpload = async (event) => {
+  event.preventDefault();
+  const form = event.currentTarget;
+  const battle = form.attributes.battle.value;
+  const button = form.querySelector("button");
+
+  button.disabled = true;
+  uploadStatus.textContent = "uploading…";
+  try {
+    const res = await fetch(`/api/upload/${encodeURIComponent(battle)}/`, {
+      method: "POST",
+      body: new FormData(form),
+    });
+    if (res.ok) {
+      window.location.reload();
+      return;
+    }
+    let detail = res.statusText;
+    try {
+      const errs = await res.json();
+      detail = errs[0].err;
+    } catch (e) {}
+    uploadStatus.textContent = `upload failed: ${detail}`;
+  } catch (e) {
+    uploadStatus.textContent = `upload failed: ${e}`;
+  } finally {
+    button.disabled = false;
+  }
+};
+
+document.getElementById("upload").addEventListener("submit", onUpload);
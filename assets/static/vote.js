@@ -15,10 +15,14 @@ const submitVote = (battleName, entryID, score) => {
 
 const onVoteFor = (event) => {
   event.preventDefault();
-  const el = event.srcElement;
-  const score = Number.parseInt(el.attributes.score.value);
+  const el = event.currentTarget;
   const entry = el.attributes.entry.value;
   const battle = el.attributes.battle.value;
+  const unique = el.attributes.unique.value === "true";
+  // clicking a selected option removes the entry from the ballot
+  const score = el.classList.contains("vote-yes")
+    ? 0
+    : Number.parseInt(el.attributes.score.value);
 
   const elementsForEntry = document.querySelectorAll(
     `button.vote[entry="${CSS.escape(entry)}"]`,
@@ -27,13 +31,17 @@ const onVoteFor = (event) => {
     e.classList.remove("vote-yes");
   }
 
-  const elementsForScore = document.querySelectorAll(
-    `button.vote[score="${CSS.escape(score)}"]`,
-  );
-  for (const e of elementsForScore) {
-    e.classList.remove("vote-yes");
+  if (score !== 0) {
+    if (unique) {
+      const elementsForScore = document.querySelectorAll(
+        `button.vote[score="${CSS.escape(score)}"]`,
+      );
+      for (const e of elementsForScore) {
+        e.classList.remove("vote-yes");
+      }
+    }
+    el.classList.add("vote-yes");
   }
-  el.classList.add("vote-yes");
 
   setTimeout(() => {
     submitVote(battle, entry, score);
@@ -44,6 +52,30 @@ for (const el of document.querySelectorAll("button.vote")) {
   el.addEventListener("click", onVoteFor);
 }
 
+const onSelectVote = (event) => {
+  const el = event.currentTarget;
+  const entry = el.attributes.entry.value;
+  const battle = el.attributes.battle.value;
+  const unique = el.attributes.unique.value === "true";
+  const score = Number.parseInt(el.value);
+
+  if (unique && score !== 0) {
+    for (const e of document.querySelectorAll("select.vote")) {
+      if (e !== el && e.value === el.value) {
+        e.value = "0";
+      }
+    }
+  }
+
+  setTimeout(() => {
+    submitVote(battle, entry, score);
+  }, 0);
+};
+
+for (const el of document.querySelectorAll("select.vote")) {
+  el.addEventListener("change", onSelectVote);
+}
+
 const submitUnvote = (battleName) => {
   const payload = {
     battle_name: battleName,
@@ -63,13 +95,57 @@ const onUnvote = (event) => {
   for (const e of document.querySelectorAll("button.vote")) {
     e.classList.remove("vote-yes");
   }
+  for (const e of document.querySelectorAll("select.vote")) {
+    e.value = "0";
+  }
 
   setTimeout(() => {
     submitUnvote(battle);
   }, 0);
 };
 
-document.querySelector("button.unvote").addEventListener("click", onUnvote);
+for (const el of document.querySelectorAll("button.unvote")) {
+  el.addEventListener("click", onUnvote);
+}
+
+// textareas with a save attribute are posted to that url with the value in
+// the field named by the field attribute.
+const submitText = async (el) => {
+  const status = el.parentElement.querySelector(".save-status");
+  const payload = {
+    battle_name: el.attributes.battle.value,
+    entry_id: el.attributes.entry.value,
+  };
+  payload[el.attributes.field.value] = el.value;
+  const res = await fetch(el.attributes.save.value, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(payload),
+  });
+  status.textContent = res.ok ? "saved" : "not saved";
+};
+
+// text is saved a moment after typing stops and when leaving the field.
+const saveTimers = new Map();
+const onTextInput = (event) => {
+  const el = event.currentTarget;
+  el.parentElement.querySelector(".save-status").textContent = "";
+  clearTimeout(saveTimers.get(el));
+  saveTimers.set(
+    el,
+    setTimeout(() => submitText(el), 1500),
+  );
+};
+const onTextChange = (event) => {
+  const el = event.currentTarget;
+  clearTimeout(saveTimers.get(el));
+  submitText(el);
+};
+
+for (const el of document.querySelectorAll("textarea[save]")) {
+  el.addEventListener("input", onTextInput);
+  el.addEventListener("change", onTextChange);
+}
 
 const toggleNotes = (event) => {
   const notesElements = Array.from(document.querySelectorAll(".notes"));
@@ -2,20 +2,34 @@ package main
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/rs/xid"
+	"github.com/some-programs/battlr/pkg/db"
 )
 
 // clientIDMiddleware .
 type clientIDMiddleware struct {
-	h http.Handler
+	h      http.Handler
+	lookup SessionLookup
 }
 
 type clientIDContextKey string
 
-var ClientIDContextKey = clientIDContextKey("clientid")
+var (
+	ClientIDContextKey = clientIDContextKey("clientid")
+	UserContextKey     = clientIDContextKey("user")
+)
+
+// sessionCookieName is the cookie holding the session token of a logged in
+// user.
+const sessionCookieName = "battlr-session"
+
+// SessionLookup returns the user logged in with a session token, nil if the
+// session is not valid.
+type SessionLookup func(token string) (*db.User, error)
 
 func (b clientIDMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var id string
@@ -43,18 +57,39 @@ func (b clientIDMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 	ctx = context.WithValue(ctx, ClientIDContextKey, id)
+	if c, err := r.Cookie(sessionCookieName); err == nil && b.lookup != nil {
+		user, err := b.lookup(c.Value)
+		if err != nil {
+			slog.Error("session lookup failed", "err", err)
+		}
+		if user != nil {
+			ctx = context.WithValue(ctx, UserContextKey, user)
+		}
+	}
 	r = r.WithContext(ctx)
 	b.h.ServeHTTP(w, r)
 }
 
-func ClientIDMiddleware() func(h http.Handler) clientIDMiddleware {
+// ClientIDMiddleware identifies the client by a cookie, or by the account
+// when the client is logged in and lookup is set.
+func ClientIDMiddleware(lookup SessionLookup) func(h http.Handler) clientIDMiddleware {
 	fn := func(h http.Handler) clientIDMiddleware {
-		return clientIDMiddleware{h: h}
+		return clientIDMiddleware{h: h, lookup: lookup}
 	}
 	return fn
 }
 
+// getClientID returns the voter id of the client, the account voter id if
+// the client is logged in.
 func getClientID(ctx context.Context) string {
+	if user := getUser(ctx); user != nil {
+		return user.VoterID()
+	}
+	return getCookieClientID(ctx)
+}
+
+// getCookieClientID returns the voter id of the browser cookie.
+func getCookieClientID(ctx context.Context) string {
 	var clientID string
 
 	if v := ctx.Value(ClientIDContextKey); v != nil {
@@ -64,3 +99,9 @@ func getClientID(ctx context.Context) string {
 	}
 	return "cookie:" + clientID
 }
+
+// getUser returns the logged in user, nil if the client is not logged in.
+func getUser(ctx context.Context) *db.User {
+	user, _ := ctx.Value(UserContextKey).(*db.User)
+	return user
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/some-programs/battlr/pkg/db"
+)
+
+// CommentRequest sets the comment of the voter on an entry, an empty Comment
+// removes it.
+type CommentRequest struct {
+	BattleName string `json:"battle_name"`
+	EntryID    string `json:"entry_id"`
+	Comment    string `json:"comment"`
+}
+
+func (s *Server) Comment() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+
+		clientID := getClientID(ctx)
+		if clientID == "" {
+			return errors.New("no client id found")
+		}
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		var req CommentRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			WriteJSONResponse(ctx, w, http.StatusBadRequest, inspectError(err))
+			return nil
+		}
+
+		battle, err := s.DB.GetBattle(req.BattleName)
+		if err != nil {
+			return err
+		}
+		if battle == nil || (!s.Unrestricted && battle.Phase == db.PhaseHidden) {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		if !s.Unrestricted && !battle.IsVotingOpen() {
+			w.WriteHeader(http.StatusForbidden)
+			return nil
+		}
+
+		voterID, err := s.ballotVoter(w, r, *battle)
+		if err != nil || voterID == "" {
+			return err
+		}
+
+		err = s.DB.UpdateComment(battle.Name, req.EntryID, voterID, req.Comment)
+		switch {
+		case errors.Is(err, db.NotFound):
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		case errors.Is(err, db.InvalidComment):
+			WriteJSONResponse(ctx, w, http.StatusBadRequest, inspectError(err))
+			return nil
+		case err != nil:
+			return err
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("{}"))
+		return nil
+	}
+}
+
+// GetComments lists all comments of a battle with the voter ids for
+// moderation.
+func (s *Server) GetComments() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		comments, err := s.DB.GetComments(r.PathValue("name"))
+		if err != nil {
+			return err
+		}
+		if comments == nil {
+			comments = []db.EntryComment{}
+		}
+		WriteJSONResponse(r.Context(), w, http.StatusOK, comments)
+		return nil
+	}
+}
+
+// ModerateComment hides or shows a comment, the action is hide or show.
+func (s *Server) ModerateComment() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		var hidden bool
+		switch r.PathValue("action") {
+		case "hide":
+			hidden = true
+		case "show":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		err := s.DB.SetCommentHidden(r.PathValue("name"), r.PathValue("entry"), r.PathValue("voter"), hidden)
+		if errors.Is(err, db.NotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		return err
+	}
+}
+
+// visibleComments returns the comments the voter can read on the results
+// page keyed by entry id. With private comments an entrant only sees the
+// comments on their own entries.
+func visibleComments(battle db.Battle, votes []db.Votes, voterID string) map[string][]db.Comment {
+	comments := db.VisibleComments(votes)
+	if battle.Comments == db.CommentsPublic {
+		return comments
+	}
+	own := make(map[string][]db.Comment)
+	for _, e := range battle.Entries {
+		if e.IsEntrant(voterID) {
+			own[e.ID] = comments[e.ID]
+		}
+	}
+	return own
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/some-programs/battlr/pkg/db"
+)
+
+func TestVisibleCommentsCommentOnlyBallot(t *testing.T) {
+	battle := db.Battle{
+		Comments: db.CommentsPublic,
+		Entries:  db.Entries{{ID: "a"}, {ID: "b"}},
+	}
+	ballots := []db.Votes{
+		{VoterID: "cookie:x", Scores: db.ScoreMap{}, Comments: map[string]db.Comment{"a": {Text: "nice"}}},
+		{VoterID: "cookie:y", Scores: db.ScoreMap{"a": 3}},
+	}
+	comments := visibleComments(battle, ballots, "cookie:z")
+	if len(comments["a"]) != 1 || comments["a"][0].Text != "nice" {
+		t.Errorf("comments = %+v, want the comment of the ballot without scores", comments)
+	}
+}
+
+func TestVisibleCommentsPrivate(t *testing.T) {
+	battle := db.Battle{
+		Comments: db.CommentsPrivate,
+		Entries: db.Entries{
+			{ID: "a", EntrantID: "invite:1"},
+			{ID: "b", UploaderID: "user:bo"},
+			{ID: "c"},
+		},
+	}
+	ballots := []db.Votes{{
+		VoterID: "cookie:x",
+		Scores:  db.ScoreMap{"a": 3},
+		Comments: map[string]db.Comment{
+			"a": {Text: "for a"},
+			"b": {Text: "for b"},
+			"c": {Text: "for c"},
+		},
+	}}
+	tests := []struct {
+		voterID string
+		want    []string
+	}{
+		{"invite:1", []string{"a"}},
+		{"user:bo", []string{"b"}},
+		{"cookie:x", nil},
+	}
+	for _, tt := range tests {
+		comments := visibleComments(battle, ballots, tt.voterID)
+		if len(comments) != len(tt.want) {
+			t.Errorf("%s: comments = %+v, want on %v", tt.voterID, comments, tt.want)
+			continue
+		}
+		for _, id := range tt.want {
+			if len(comments[id]) != 1 || comments[id][0].Text != "for "+id {
+				t.Errorf("%s: comments on %s = %+v", tt.voterID, id, comments[id])
+			}
+		}
+	}
+}
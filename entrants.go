@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/some-programs/battlr/pkg/db"
+)
+
+// EntrantRequest links an entry to the voter id of its entrant, for example
+// user:{username} for an account or invite:{id} for an invite.
+type EntrantRequest struct {
+	VoterID string `json:"voter_id"`
+}
+
+func (s *Server) PutEntrant() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		var req EntrantRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			WriteJSONResponse(r.Context(), w, http.StatusBadRequest, inspectError(err))
+			return nil
+		}
+		if req.VoterID == "" {
+			WriteJSONResponse(r.Context(), w, http.StatusBadRequest, inspectError(errors.New("voter_id is required")))
+			return nil
+		}
+		return s.setEntrant(w, r, req.VoterID)
+	}
+}
+
+func (s *Server) DeleteEntrant() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		return s.setEntrant(w, r, "")
+	}
+}
+
+func (s *Server) setEntrant(w http.ResponseWriter, r *http.Request, voterID string) error {
+	err := s.DB.SetEntrant(r.PathValue("name"), r.PathValue("entry"), voterID)
+	if errors.Is(err, db.NotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return nil
+	}
+	return err
+}
+
+// ownEntries returns the ids of the entries of the voter.
+func ownEntries(battle db.Battle, voterID string) map[string]bool {
+	own := make(map[string]bool)
+	for _, e := range battle.Entries {
+		if e.IsEntrant(voterID) {
+			own[e.ID] = true
+		}
+	}
+	return own
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/some-programs/battlr/pkg/db"
+)
+
+// ResultsExport is the machine readable result of a battle.
+type ResultsExport struct {
+	Battle    string    `json:"battle"`
+	Title     string    `json:"title"`
+	Scoring   string    `json:"scoring"`
+	ClosedAt  time.Time `json:"closed_at"`
+	NumVoters int       `json:"num_voters"`
+	// Weighted is set if the scores are combined from the voter roles, in
+	// hundredths of a percent.
+	Weighted bool `json:"weighted,omitempty"`
+	// Values are the possible ballot values of the scoring system.
+	Values  []int          `json:"values"`
+	Entries []ExportEntry  `json:"entries"`
+	Ballots []ExportBallot `json:"ballots,omitempty"`
+
+	// topPlaces is the number of places listed on the results page.
+	topPlaces int
+}
+
+type ExportEntry struct {
+	// Place is the 1-based place, 0 if the place is not published.
+	Place  int    `json:"place"`
+	ID     string `json:"id"`
+	Author string `json:"author"`
+	Title  string `json:"title"`
+	Score  int    `json:"score"`
+	// Voters is the number of ballots the entry is on.
+	Voters int `json:"voters"`
+	// Breakdown is the number of ballots giving each value to the entry.
+	Breakdown map[int]int `json:"breakdown"`
+	// Penalized and Disqualified are set if the entrant did not cast a full
+	// ballot, disqualified entries have no place.
+	Penalized    bool `json:"penalized,omitempty"`
+	Disqualified bool `json:"disqualified,omitempty"`
+}
+
+// status returns the outcome of the participation rule for the entry.
+func (e ExportEntry) status() string {
+	switch {
+	case e.Disqualified:
+		return "disqualified"
+	case e.Penalized:
+		return "penalized"
+	}
+	return ""
+}
+
+// ExportBallot is a ballot, in public exports Voter is a sequence number and
+// the times are left out.
+type ExportBallot struct {
+	Voter     string      `json:"voter"`
+	CreatedAt *time.Time  `json:"created_at,omitempty"`
+	UpdatedAt *time.Time  `json:"updated_at,omitempty"`
+	Scores    db.ScoreMap `json:"scores"`
+}
+
+// newResultsExport builds the full export of a battle including voter ids.
+func newResultsExport(battle db.Battle, votes []db.Votes) ResultsExport {
+	system := battle.ScoringSystem()
+	results := battle.Results(votes)
+	exp := ResultsExport{
+		Battle:    battle.Name,
+		Title:     battle.DisplayName(),
+		Scoring:   system.Name(),
+		ClosedAt:  battle.ClosedAt(),
+		NumVoters: len(votes),
+		Weighted:  battle.Weighted(),
+		Entries:   []ExportEntry{},
+		topPlaces: len(results.Places.Top(resultsTopPlaces, system)),
+	}
+	for _, opt := range system.Options(len(battle.Entries)) {
+		exp.Values = append(exp.Values, opt.Value)
+	}
+	newEntry := func(place int, e db.Entry) ExportEntry {
+		entry := ExportEntry{
+			Place:     place,
+			ID:        e.ID,
+			Author:    e.Author,
+			Title:     e.Title,
+			Score:     results.Scores[e.ID],
+			Breakdown: make(map[int]int),
+			Penalized: results.Penalized[e.ID],
+		}
+		for _, v := range votes {
+			if value, ok := v.Scores[e.ID]; ok && value != 0 {
+				entry.Voters++
+				entry.Breakdown[value]++
+			}
+		}
+		return entry
+	}
+	for placeIdx, place := range results.Places {
+		for _, e := range place.Entries {
+			exp.Entries = append(exp.Entries, newEntry(placeIdx+1, e))
+		}
+	}
+	for _, e := range results.Disqualified {
+		entry := newEntry(0, e)
+		entry.Disqualified = true
+		exp.Entries = append(exp.Entries, entry)
+	}
+	for _, v := range votes {
+		exp.Ballots = append(exp.Ballots, ExportBallot{
+			Voter:     v.VoterID,
+			CreatedAt: &v.CreatedAt,
+			UpdatedAt: &v.UpdatedAt,
+			Scores:    v.Scores,
+		})
+	}
+	return exp
+}
+
+// public returns the export with voters anonymized and with the places the
+// results page does not show left out.
+func (exp ResultsExport) public(config ServerConfig) ResultsExport {
+	res := exp
+	res.Entries = slices.Clone(exp.Entries)
+	// ballots are ordered by a hash of the voter id so that their order
+	// does not tell when they were cast.
+	ballots := slices.Clone(exp.Ballots)
+	slices.SortFunc(ballots, func(a, b ExportBallot) int {
+		ha, hb := sha256.Sum256([]byte(a.Voter)), sha256.Sum256([]byte(b.Voter))
+		return bytes.Compare(ha[:], hb[:])
+	})
+	res.Ballots = nil
+	for i, b := range ballots {
+		res.Ballots = append(res.Ballots, ExportBallot{
+			Voter:  strconv.Itoa(i + 1),
+			Scores: b.Scores,
+		})
+	}
+
+	if !config.FullResultsOrder {
+		for i, e := range res.Entries {
+			if e.Place > exp.topPlaces {
+				res.Entries[i].Place = 0
+			}
+		}
+		// like on the results page the other entries are ordered by name.
+		slices.SortStableFunc(res.Entries, func(a, b ExportEntry) int {
+			switch {
+			case a.Place != 0 && b.Place != 0:
+				return cmp.Compare(a.Place, b.Place)
+			case a.Place != 0:
+				return -1
+			case b.Place != 0:
+				return 1
+			}
+			if v := cmp.Compare(a.Author, b.Author); v != 0 {
+				return v
+			}
+			return cmp.Compare(a.Title, b.Title)
+		})
+	}
+	return res
+}
+
+// writeResultsCSV writes one row per entry with a column for the number of
+// ballots giving each value.
+func writeResultsCSV(w http.ResponseWriter, exp ResultsExport, name string) error {
+	setCSVHeaders(w, name)
+	cw := csv.NewWriter(w)
+	header := []string{"place", "id", "author", "title", "score", "voters"}
+	for _, v := range exp.Values {
+		header = append(header, fmt.Sprintf("votes_%d", v))
+	}
+	header = append(header, "status")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, e := range exp.Entries {
+		row := []string{
+			strconv.Itoa(e.Place), e.ID, e.Author, e.Title,
+			strconv.Itoa(e.Score), strconv.Itoa(e.Voters),
+		}
+		for _, v := range exp.Values {
+			row = append(row, strconv.Itoa(e.Breakdown[v]))
+		}
+		row = append(row, e.status())
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeBallotsCSV writes one row per ballot with a column for each entry.
+func writeBallotsCSV(w http.ResponseWriter, exp ResultsExport, name string) error {
+	setCSVHeaders(w, name)
+	cw := csv.NewWriter(w)
+	header := []string{"voter", "created_at", "updated_at"}
+	for _, e := range exp.Entries {
+		header = append(header, e.ID)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, b := range exp.Ballots {
+		row := []string{b.Voter, formatOptionalTime(b.CreatedAt), formatOptionalTime(b.UpdatedAt)}
+		for _, e := range exp.Entries {
+			value := ""
+			if v, ok := b.Scores[e.ID]; ok {
+				value = strconv.Itoa(v)
+			}
+			row = append(row, value)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func setCSVHeaders(w http.ResponseWriter, name string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+}
+
+// loadExport returns the full export of a battle, nil if the battle does not
+// exist.
+func (s *Server) loadExport(battleName string) (*db.Battle, *ResultsExport, error) {
+	battle, err := s.DB.GetBattle(battleName)
+	if err != nil || battle == nil {
+		return nil, nil, err
+	}
+	votes, err := s.DB.GetAllVotes(battleName)
+	if err != nil {
+		return nil, nil, err
+	}
+	exp := newResultsExport(*battle, votes)
+	return battle, &exp, nil
+}
+
+// ExportResults serves the public results of a closed battle as results.json,
+// results.csv or ballots.csv.
+func (s *Server) ExportResults() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		battle, exp, err := s.loadExport(r.PathValue("name"))
+		if err != nil {
+			return err
+		}
+		if battle == nil || (!s.Unrestricted && battle.Phase == db.PhaseHidden) {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		if !s.Unrestricted {
+			places, err := s.topPlaces(battle)
+			if err != nil {
+				return err
+			}
+			if battle.Phase != db.PhaseResults || battle.Reveal.Hidden(len(places)) > 0 {
+				w.WriteHeader(http.StatusForbidden)
+				return nil
+			}
+		}
+		return s.writeExport(w, r, exp.public(s.ServerConfig), battle.Name)
+	}
+}
+
+// AdminExportResults serves the results with the full ballots in any phase.
+func (s *Server) AdminExportResults() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		battle, exp, err := s.loadExport(r.PathValue("name"))
+		if err != nil {
+			return err
+		}
+		if battle == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		return s.writeExport(w, r, *exp, battle.Name)
+	}
+}
+
+func (s *Server) writeExport(w http.ResponseWriter, r *http.Request, exp ResultsExport, battleName string) error {
+	switch file := r.PathValue("file"); file {
+	case "results.json":
+		WriteJSONResponse(r.Context(), w, http.StatusOK, exp)
+		return nil
+	case "results.csv":
+		return writeResultsCSV(w, exp, battleName+"-results.csv")
+	case "ballots.csv":
+		return writeBallotsCSV(w, exp, battleName+"-ballots.csv")
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return nil
+	}
+}
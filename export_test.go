@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/some-programs/battlr/pkg/db"
+)
+
+func TestResultsExportPublicScores(t *testing.T) {
+	exp := ResultsExport{
+		Entries: []ExportEntry{
+			{Place: 1, ID: "a", Score: 5, Voters: 2, Breakdown: map[int]int{3: 1, 2: 1}},
+			{Place: 2, ID: "b", Score: 1, Voters: 1, Breakdown: map[int]int{1: 1}},
+		},
+		Ballots: []ExportBallot{
+			{Voter: "cookie:x", Scores: db.ScoreMap{"a": 3}},
+			{Voter: "cookie:y", Scores: db.ScoreMap{"a": 2, "b": 1}},
+		},
+		topPlaces: 1,
+	}
+	tests := []struct {
+		name   string
+		config ServerConfig
+		placeB int
+	}{
+		{"default", ServerConfig{}, 0},
+		{"scores shown on the page", ServerConfig{ShowScores: true}, 0},
+		{"full results order", ServerConfig{FullResultsOrder: true}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := exp.public(tt.config)
+			a := res.Entries[0]
+			if a.Score != 5 || a.Voters != 2 || a.Breakdown[3] != 1 {
+				t.Errorf("entry = %+v, want the score, voters and breakdown", a)
+			}
+			if got := res.Entries[1].Place; got != tt.placeB {
+				t.Errorf("place of b = %d, want %d", got, tt.placeB)
+			}
+			if got := len(res.Ballots); got != 2 {
+				t.Errorf("%d ballots, want 2", got)
+			}
+			for _, b := range res.Ballots {
+				if b.Voter != "1" && b.Voter != "2" {
+					t.Errorf("voter %q is not anonymized", b.Voter)
+				}
+			}
+		})
+	}
+}
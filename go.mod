@@ -8,7 +8,9 @@ require (
 	github.com/gorilla/websocket v1.5.1
 	github.com/peterbourgon/ff/v3 v3.4.0
 	github.com/rs/xid v1.5.0
+	github.com/yuin/goldmark v1.7.4
 	go.etcd.io/bbolt v1.3.9
+	golang.org/x/crypto v0.22.0
 	golang.org/x/sync v0.7.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -3,6 +3,7 @@ package main
 import (
 	"archive/zip"
 	"bytes"
+	"cmp"
 	"context"
 	"crypto/sha256"
 	"encoding/json"
@@ -13,17 +14,21 @@ import (
 	"io/fs"
 	"log"
 	"log/slog"
+	"math"
 	"math/rand/v2"
 	"net/http"
 	"net/url"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/some-programs/battlr/assets"
 	"github.com/some-programs/battlr/pkg/db"
+	"github.com/some-programs/battlr/pkg/events"
 	"github.com/some-programs/battlr/pkg/scanner"
 	"golang.org/x/sync/errgroup"
 )
@@ -70,33 +75,89 @@ type ServerConfig struct {
 	Unrestricted     bool
 	ShowScores       bool
 	FullResultsOrder bool
+	// MaxUploadSize is the largest accepted entry upload in bytes.
+	MaxUploadSize int64
+	// RequireAccount only allows logged in users to vote.
+	RequireAccount bool
+	// TrustProxy takes client addresses from the X-Forwarded-For header
+	// set by a reverse proxy.
+	TrustProxy bool
 }
 
+// actorAPI is recorded as the actor of phase transitions made through the
+// admin api.
+const actorAPI = "api"
+
 type Server struct {
 	ServerConfig
 	DB          *db.DB
 	BattlesFsys fs.FS
+	// BattlesDir is the directory of BattlesFsys, uploaded entries are
+	// written to it.
+	BattlesDir string
+
+	// scanMu serializes scans started from the api and the directory watcher.
+	scanMu sync.Mutex
 }
 
 func (server *Server) RegisterHandlers(h *http.ServeMux, apiKey string, battlesFsys fs.FS) {
 	authMiddleware := BearerAuthMiddleware(apiKey)
+	clientMiddleware := ClientIDMiddleware(server.DB.GetSessionUser)
 	h.Handle("GET /battles/", server.Index())
-	h.Handle("GET /battles/vote/{name}/", ClientIDMiddleware()(server.VoteForm()))
-	h.Handle("GET /zip/{name}/", server.Zip())
-	h.Handle("GET /battles/results/{name}/", ClientIDMiddleware()(server.Results()))
+	h.Handle("GET /battles/vote/{name}/", clientMiddleware(server.VoteForm()))
+	h.Handle("POST /battles/invite/{name}/", clientMiddleware(server.AcceptInvite()))
+	h.Handle("GET /zip/{name}/", clientMiddleware(server.Zip()))
+	h.Handle("GET /battles/submit/{name}/", clientMiddleware(server.SubmitForm()))
+	h.Handle("GET /battles/results/{name}/", clientMiddleware(server.Results()))
+	h.Handle("GET /battles/reveal/{name}/", server.RevealPresenter())
+	h.Handle("GET /battles/stats/{name}/", server.StatsPage())
+	h.Handle("GET /battles/notes/{name}/", clientMiddleware(server.DownloadNotes()))
+	h.Handle("GET /seasons/{name}/", server.SeasonPage())
+	h.Handle("GET /artists/{name}/", server.ArtistPage())
+	h.Handle("GET /export/{name}/{file}", server.ExportResults())
 	h.Handle("GET /events/{name}/", server.battleEvents())
 	h.Handle("GET /static/", http.FileServerFS(assets.StaticHashFS))
-
-	h.Handle("/api/vote/", ClientIDMiddleware()(server.Vote()))
-	h.Handle("/api/unvote/", ClientIDMiddleware()(server.UnVote()))
+	h.Handle("GET /account/", clientMiddleware(server.AccountPage()))
+	h.Handle("POST /account/login/", clientMiddleware(server.Login()))
+	h.Handle("POST /account/register/", clientMiddleware(server.Register()))
+	h.Handle("POST /account/logout/", server.Logout())
+
+	h.Handle("/api/vote/", clientMiddleware(server.Vote()))
+	h.Handle("/api/unvote/", clientMiddleware(server.UnVote()))
+	h.Handle("POST /api/comment/", clientMiddleware(server.Comment()))
+	h.Handle("POST /api/notes/", clientMiddleware(server.UpdateNote()))
+	h.Handle("POST /api/upload/{name}/", clientMiddleware(server.Upload()))
+	h.Handle("POST /api/listen/{name}/{entry}/", clientMiddleware(server.Listen()))
 
 	h.Handle("/api/battles/{name}/", authMiddleware(server.GetBattleData()))
+	h.Handle("GET /api/seasons/{name}/", server.GetSeasonResults())
+	h.Handle("PUT /api/seasons/{name}/", authMiddleware(server.PutSeason()))
+	h.Handle("DELETE /api/seasons/{name}/", authMiddleware(server.DeleteSeason()))
+	h.Handle("GET /api/export/{name}/{file}", authMiddleware(server.AdminExportResults()))
+	h.Handle("GET /api/aliases/", authMiddleware(server.GetAliases()))
+	h.Handle("PUT /api/aliases/{alias}/", authMiddleware(server.PutAlias()))
+	h.Handle("DELETE /api/aliases/{alias}/", authMiddleware(server.DeleteAlias()))
 	h.Handle("/api/scan/", authMiddleware(server.Scan()))
 	h.Handle("/api/open/{name}/", authMiddleware(server.OpenBattle()))
 	h.Handle("/api/close/{name}/", authMiddleware(server.CloseBattle()))
 	h.Handle("/api/hide/{name}/", authMiddleware(server.HideBattle()))
 	h.Handle("/api/unhide/{name}/", authMiddleware(server.UnhideBattle()))
-	h.Handle("/dl/", http.StripPrefix("/dl/", server.ResolveFilename(http.FileServerFS(battlesFsys))))
+	h.Handle("POST /api/phase/{name}/{phase}/", authMiddleware(server.SetPhase()))
+	h.Handle("POST /api/schedule/{name}/", authMiddleware(server.ScheduleBattle()))
+	h.Handle("GET /api/comments/{name}/", authMiddleware(server.GetComments()))
+	h.Handle("POST /api/comments/{name}/{entry}/{voter}/{action}/", authMiddleware(server.ModerateComment()))
+	h.Handle("PUT /api/entrants/{name}/{entry}/", authMiddleware(server.PutEntrant()))
+	h.Handle("DELETE /api/entrants/{name}/{entry}/", authMiddleware(server.DeleteEntrant()))
+	h.Handle("PUT /api/roles/{name}/{voter}/", authMiddleware(server.PutVoterRole()))
+	h.Handle("DELETE /api/roles/{name}/{voter}/", authMiddleware(server.DeleteVoterRole()))
+	h.Handle("GET /api/review/{name}/", authMiddleware(server.ReviewBallots()))
+	h.Handle("POST /api/ballots/{name}/{voter}/{action}/", authMiddleware(server.ExcludeBallot()))
+	h.Handle("GET /api/invites/{name}/", authMiddleware(server.GetInvites()))
+	h.Handle("POST /api/invites/{name}/", authMiddleware(server.CreateInvites()))
+	h.Handle("DELETE /api/invites/{name}/{id}/", authMiddleware(server.DeleteInvite()))
+	h.Handle("GET /api/reveal/{name}/", authMiddleware(server.GetReveal()))
+	h.Handle("POST /api/reveal/{name}/{action}/", authMiddleware(server.UpdateReveal()))
+	h.Handle("/dl/", clientMiddleware(http.StripPrefix("/dl/", server.ResolveFilename(http.FileServerFS(battlesFsys)))))
 
 	h.HandleFunc("GET /robots.txt", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -119,12 +180,21 @@ func (s *Server) ResolveFilename(h http.Handler) http.Handler {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
+		if !s.Unrestricted && !battle.Phase.AllowsListening() {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
 
 		entry, ok := battle.GetEntryByID(entryID)
 		if !ok {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
+		if !isPreview(r) {
+			if err := s.DB.RecordListen(battle.Name, entry.ID, getCookieClientID(r.Context())); err != nil {
+				slog.Error("failed to record listen", "err", err)
+			}
+		}
 
 		p := strings.Replace(r.URL.Path, entryID, entry.Filename, 1)
 		rp := strings.Replace(r.URL.RawPath, entryID, entry.Filename, 1)
@@ -139,6 +209,26 @@ func (s *Server) ResolveFilename(h http.Handler) http.Handler {
 	})
 }
 
+// isPreview reports whether r is a player loading a file from its start.
+// Players do so to probe it, to preload it or to start playback, so such
+// requests are not counted as listens. The vote page reports plays to Listen
+// instead.
+func isPreview(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return true
+	}
+	spec, ok := strings.CutPrefix(r.Header.Get("Range"), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return false
+	}
+	start, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return false
+	}
+	first, err := strconv.ParseInt(start, 10, 64)
+	return err == nil && first == 0
+}
+
 func (s *Server) Index() AppHandler {
 	tmpl, err := template.New("base.html").
 		Funcs(template.FuncMap{
@@ -161,18 +251,25 @@ func (s *Server) Index() AppHandler {
 
 		var battles []db.Battle
 		for _, b := range allBattles {
-			if b.Hidden {
+			if b.Phase == db.PhaseHidden {
 				continue
 			}
 			battles = append(battles, b)
 		}
 
+		seasons, err := s.DB.GetAllSeasons()
+		if err != nil {
+			return err
+		}
+
 		templateData := struct {
 			Title   string
 			Battles []db.Battle
+			Seasons []db.Season
 		}{
 			Title:   "Battles",
 			Battles: battles,
+			Seasons: seasons,
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -188,13 +285,26 @@ func (s *Server) Index() AppHandler {
 func (s *Server) Results() AppHandler {
 	tmpl, err := template.New("base.html").
 		Funcs(template.FuncMap{
-			"static": assets.StaticHashFS.HashName,
+			"static":    assets.StaticHashFS.HashName,
+			"markdown":  renderMarkdown,
+			"artistkey": db.ArtistKey,
 			"add": func(i, j int) int {
 				return i + j
 			},
+			// score formats combined scores of weighted battles as a
+			// percentage.
+			"score": func(weighted bool, score int) string {
+				if weighted {
+					return fmt.Sprintf("%.2f%%", float64(score)*100/db.WeightedScoreScale)
+				}
+				return strconv.Itoa(score)
+			},
+			"percent": func(f float64) int {
+				return int(math.Round(f * 100))
+			},
 		},
 		).
-		ParseFS(assets.TemplateFS, "template/base.html", "template/battle-results.html")
+		ParseFS(assets.TemplateFS, "template/base.html", "template/battle-meta.html", "template/battle-results.html")
 	if err != nil {
 		slog.Error("failed to parse clients template",
 			"err", err,
@@ -212,11 +322,12 @@ func (s *Server) Results() AppHandler {
 		}
 
 		if !s.Unrestricted {
-			if battle.Hidden {
+			switch battle.Phase {
+			case db.PhaseHidden:
 				return db.NotFound
-			}
-			if battle.ClosedAt.IsZero() {
-				return s.ErrorPage(r.Context(), w, r, "cannot view results while voting is open", "/battles/")
+			case db.PhaseResults:
+			default:
+				return s.ErrorPage(r.Context(), w, r, "results are not published yet", "/battles/")
 			}
 		}
 
@@ -224,42 +335,105 @@ func (s *Server) Results() AppHandler {
 		if err != nil {
 			return err
 		}
-		if len(allVotes) == 0 {
+		ballots, err := s.DB.GetIncludedBallots(name)
+		if err != nil {
+			return err
+		}
+		if len(allVotes) == 0 && len(db.VisibleComments(ballots)) == 0 {
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte(`No votes recorded`))
 			return nil
 		}
-		sumScores := db.SumScores(allVotes)
+		results := battle.Results(allVotes)
+		sumScores := results.Scores
 		numVoters := len(allVotes)
 
-		topPlaces := battle.Entries.Places(sumScores)
-		if len(topPlaces) > 3 {
-			topPlaces = topPlaces[:3]
-		}
+		topPlaces := results.Places.Top(resultsTopPlaces, battle.ScoringSystem())
+		hiddenPlaces := battle.Reveal.Hidden(len(topPlaces))
 
 		if s.FullResultsOrder {
 			battle.Entries.SortByScore(sumScores)
 		} else {
 			battle.Entries.Shuffle()
 		}
-		rest := topPlaces.Diff(battle.Entries)
+		var pairwise *db.PairwiseMatrix
+		if ps, ok := battle.ScoringSystem().(db.PairwiseScoringSystem); ok {
+			m := ps.Pairwise(results.Places.All(), allVotes)
+			pairwise = &m
+		}
+
+		rest := slices.DeleteFunc(topPlaces.Diff(battle.Entries), results.Disqualified.Contains)
 		rest.SortByName()
+		disqualified := results.Disqualified
+		roles := results.Roles
+		if !s.FullResultsOrder {
+			for i, r := range roles {
+				roles[i].Places = r.Places.Top(resultsTopPlaces, battle.ScoringSystem())
+			}
+		}
+		voterID, err := s.ownerID(r.Context(), *battle)
+		if err != nil {
+			return err
+		}
+		var comments map[string][]db.Comment
+		if hiddenPlaces > 0 {
+			// the other entries, the pairwise preferences and the role
+			// rankings would give away the hidden places.
+			rest = nil
+			disqualified = nil
+			pairwise = nil
+			roles = nil
+		} else {
+			comments = visibleComments(*battle, ballots, voterID)
+		}
+		notes, err := s.DB.GetNotes(battle.Name, voterID)
+		if err != nil {
+			return err
+		}
+		var invites *db.InviteStats
+		if battle.InviteOnly {
+			stats, err := s.DB.InviteStats(battle.Name)
+			if err != nil {
+				return err
+			}
+			invites = &stats
+		}
 		templateData := struct {
-			Title     string
-			Battle    db.Battle
-			NumVoters int
-			SumScores db.ScoreMap
-			Config    ServerConfig
-			TopPlaces []db.Entries
-			Rest      db.Entries
+			Title        string
+			Battle       db.Battle
+			NumVoters    int
+			SumScores    db.ScoreMap
+			Config       ServerConfig
+			TopPlaces    db.Places
+			HiddenPlaces int
+			Rest         db.Entries
+			Pairwise     *db.PairwiseMatrix
+			Comments     map[string][]db.Comment
+			HasNotes     bool
+			Invites      *db.InviteStats
+			// Penalized and Disqualified are the outcome of the
+			// participation rule.
+			Penalized    map[string]bool
+			Disqualified db.Entries
+			// Roles are the rankings of each voter role in weighted
+			// battles.
+			Roles []db.RoleResults
 		}{
-			Title:     "Results",
-			Battle:    *battle,
-			NumVoters: numVoters,
-			SumScores: sumScores,
-			Config:    s.ServerConfig,
-			TopPlaces: topPlaces,
-			Rest:      rest,
+			Title:        "Results",
+			Battle:       *battle,
+			NumVoters:    numVoters,
+			SumScores:    sumScores,
+			Config:       s.ServerConfig,
+			TopPlaces:    topPlaces,
+			HiddenPlaces: hiddenPlaces,
+			Rest:         rest,
+			Pairwise:     pairwise,
+			Comments:     comments,
+			HasNotes:     notes != nil,
+			Invites:      invites,
+			Penalized:    results.Penalized,
+			Disqualified: disqualified,
+			Roles:        roles,
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -278,7 +452,8 @@ func (s *Server) VoteForm() AppHandler {
 
 	tmpl, err := template.New("base.html").
 		Funcs(template.FuncMap{
-			"static": assets.StaticHashFS.HashName,
+			"static":   assets.StaticHashFS.HashName,
+			"markdown": renderMarkdown,
 			"add": func(i, j int) int {
 				return i + j
 			},
@@ -300,7 +475,7 @@ func (s *Server) VoteForm() AppHandler {
 			},
 		},
 		).
-		ParseFS(assets.TemplateFS, "template/base.html", "template/battle-vote.html")
+		ParseFS(assets.TemplateFS, "template/base.html", "template/battle-meta.html", "template/battle-vote.html")
 	if err != nil {
 		slog.Error("failed to parse clients template",
 			"err", err,
@@ -319,14 +494,22 @@ func (s *Server) VoteForm() AppHandler {
 		}
 
 		if !s.Unrestricted {
-			if battle.Hidden {
+			switch battle.Phase {
+			case db.PhaseHidden:
 				return db.NotFound
-			}
-			if !battle.ClosedAt.IsZero() {
-				return errors.New("voting is closed")
+			case db.PhaseSubmission:
+				return s.ErrorPage(r.Context(), w, r, "the battle is accepting entries", "/battles/submit/"+battle.Name+"/")
+			case db.PhaseResults:
+				return s.ErrorPage(r.Context(), w, r, "voting is closed", "/battles/results/"+battle.Name+"/")
 			}
 		}
 
+		if token := r.URL.Query().Get("token"); token != "" && battle.InviteOnly {
+			// link previews fetch the url, the invite is only redeemed when
+			// the voter confirms it.
+			return s.renderInvite(w, *battle, token)
+		}
+
 		shuffleSeedStr := r.URL.Query().Get("shuffle")
 
 		if shuffleSeedStr == "" {
@@ -354,25 +537,81 @@ func (s *Server) VoteForm() AppHandler {
 			return errors.New("no client id found")
 		}
 
-		votes, err := s.DB.GetVotes(battle.Name, clientID)
-		if err != nil && err != db.NotFound {
+		voterID, err := s.voterID(ctx, *battle)
+		if err != nil {
 			return err
 		}
 
+		var votes *db.Votes
+		if voterID != "" {
+			votes, err = s.DB.GetVotes(battle.Name, voterID)
+			if err != nil && err != db.NotFound {
+				return err
+			}
+		}
+
 		if votes == nil {
 			votes = &db.Votes{}
 		}
+		notes, err := s.DB.GetNotes(battle.Name, cmp.Or(voterID, clientID))
+		if err != nil {
+			return err
+		}
+		if notes == nil {
+			notes = &db.Notes{}
+		}
+
+		system := battle.ScoringSystem()
 
 		templateData := struct {
-			Title  string
-			Battle db.Battle
-			Votes  db.Votes
-			Config ServerConfig
+			Title   string
+			Battle  db.Battle
+			Votes   db.Votes
+			Notes   map[string]string
+			User    *db.User
+			Config  ServerConfig
+			Scoring string
+			Options []db.VoteOption
+			Unique  bool
+			CanVote bool
+			// MaxCommentLength limits the comment fields.
+			MaxCommentLength int
+			// AccountURL is set if voting requires logging in.
+			AccountURL string
+			// NeedsInvite is set if voting requires an invite the client
+			// has not redeemed.
+			NeedsInvite bool
+			// OwnEntries are the entries of the voter which they cannot
+			// vote for.
+			OwnEntries map[string]bool
+			// FullBallot is the number of entries an entrant has to vote
+			// for under the participation rule, 0 for other voters.
+			FullBallot int
 		}{
-			Title:  "Voting",
-			Battle: *battle,
-			Votes:  *votes,
-			Config: s.ServerConfig,
+			Title:   "Voting",
+			Battle:  *battle,
+			Votes:   *votes,
+			Notes:   notes.Notes,
+			User:    getUser(ctx),
+			Config:  s.ServerConfig,
+			Scoring: system.Name(),
+			Options: system.Options(len(battle.Entries)),
+			Unique:  system.Unique(),
+			CanVote: battle.IsVotingOpen() || s.Unrestricted,
+
+			MaxCommentLength: db.MaxCommentLength,
+			OwnEntries:       ownEntries(*battle, voterID),
+		}
+		switch {
+		case battle.InviteOnly && voterID == "":
+			templateData.CanVote = false
+			templateData.NeedsInvite = true
+		case !battle.InviteOnly && s.accountRequired(r):
+			templateData.CanVote = false
+			templateData.AccountURL = accountURL(r)
+		}
+		if battle.Participation.Enabled() && len(templateData.OwnEntries) > 0 {
+			templateData.FullBallot = battle.FullBallotSize(voterID)
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -431,15 +670,30 @@ func (s *Server) Vote() AppHandler {
 		}
 
 		if !s.Unrestricted {
-			if battle.Hidden {
+			switch battle.Phase {
+			case db.PhaseHidden:
 				return db.NotFound
-			}
-			if !battle.ClosedAt.IsZero() {
-				return s.ErrorPage(r.Context(), w, r, "voting is closed", "/battles/")
+			case db.PhaseVoting:
+			default:
+				w.WriteHeader(http.StatusForbidden)
+				return nil
 			}
 		}
 
-		if err := s.DB.UpdateVote(req.BattleName, req.EntryID, clientID, req.Score); err != nil {
+		voterID, err := s.ballotVoter(w, r, *battle)
+		if err != nil || voterID == "" {
+			return err
+		}
+
+		ballotReq, err := s.ballotRequest(r, battle.Name)
+		if err != nil {
+			return err
+		}
+		if err := s.DB.UpdateVote(req.BattleName, req.EntryID, voterID, req.Score, ballotReq); err != nil {
+			if errors.Is(err, db.SelfVote) {
+				WriteJSONResponse(ctx, w, http.StatusForbidden, inspectError(err))
+				return nil
+			}
 			return err
 		}
 
@@ -484,7 +738,15 @@ func (s *Server) UnVote() AppHandler {
 			w.WriteHeader(http.StatusNotFound)
 			return nil
 		}
-		if err := s.DB.RemoveVotes(req.BattleName, clientID); err != nil {
+		if !s.Unrestricted && !battle.IsVotingOpen() {
+			w.WriteHeader(http.StatusForbidden)
+			return nil
+		}
+		voterID, err := s.ballotVoter(w, r, *battle)
+		if err != nil || voterID == "" {
+			return err
+		}
+		if err := s.DB.RemoveVotes(req.BattleName, voterID); err != nil {
 			return err
 		}
 
@@ -506,12 +768,19 @@ func (s *Server) Zip() AppHandler {
 			}
 			return err
 		}
+		if battle == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
 
+		// The zip file contains the original filenames which usually
+		// include the author names.
 		if !s.Unrestricted {
-			if battle.Hidden {
+			switch battle.Phase {
+			case db.PhaseHidden:
 				return db.NotFound
-			}
-			if battle.IsVotingOpen() {
+			case db.PhaseResults:
+			default:
 				w.WriteHeader(http.StatusForbidden)
 				return nil
 			}
@@ -520,6 +789,13 @@ func (s *Server) Zip() AppHandler {
 		if err != nil {
 			return err
 		}
+		var entryIDs []string
+		for _, e := range battle.Entries {
+			entryIDs = append(entryIDs, e.ID)
+		}
+		if err := s.DB.RecordListens(battle.Name, entryIDs, getCookieClientID(r.Context())); err != nil {
+			slog.Error("failed to record listens", "err", err)
+		}
 
 		w.Header().Set("Content-Type", "application/zip")
 		w.Header().Set("X-Content-Type-Options", "nosniff")
@@ -566,86 +842,127 @@ func (s *Server) Zip() AppHandler {
 }
 
 func (s *Server) CloseBattle() AppHandler {
+	return s.changePhase(s.DB.CloseBattle)
+}
+
+func (s *Server) OpenBattle() AppHandler {
+	return s.changePhase(s.DB.OpenBattle)
+}
+
+func (s *Server) HideBattle() AppHandler {
+	return s.changePhase(s.DB.HideBattle)
+}
+
+func (s *Server) UnhideBattle() AppHandler {
+	return s.changePhase(s.DB.UnhideBattle)
+}
+
+func (s *Server) SetPhase() AppHandler {
 	return func(w http.ResponseWriter, r *http.Request) error {
-		battleName := r.PathValue("name")
-		_, err := s.DB.GetBattle(battleName)
-		if err != nil {
-			if err == db.NotFound {
-				w.WriteHeader(http.StatusNotFound)
-				return nil
-			}
-			return err
-		}
-		return s.DB.CloseBattle(battleName)
+		phase := db.Phase(r.PathValue("phase"))
+		return s.changePhase(func(battleName string, actor string) error {
+			return s.DB.SetPhase(battleName, phase, actor)
+		})(w, r)
 	}
 }
 
-func (s *Server) OpenBattle() AppHandler {
+// changePhase returns an admin handler which applies fn to the named battle.
+func (s *Server) changePhase(fn func(battleName string, actor string) error) AppHandler {
 	return func(w http.ResponseWriter, r *http.Request) error {
 		battleName := r.PathValue("name")
-		_, err := s.DB.GetBattle(battleName)
-		if err != nil {
-			if err == db.NotFound {
-				w.WriteHeader(http.StatusNotFound)
-				return nil
-			}
-			return err
+		err := fn(battleName, actorAPI)
+		switch {
+		case errors.Is(err, db.NotFound):
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		case errors.Is(err, db.InvalidTransition):
+			WriteJSONResponse(r.Context(), w, http.StatusConflict, inspectError(err))
+			return nil
 		}
-		return s.DB.OpenBattle(battleName)
+		return err
 	}
 }
 
-func (s *Server) HideBattle() AppHandler {
+// ScheduleRequest sets the scheduled voting times of a battle, omitted times
+// remove the schedule. Times set in battle.yaml replace these on the next
+// scan.
+type ScheduleRequest struct {
+	OpensAt  time.Time `json:"opens_at"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+func (s *Server) ScheduleBattle() AppHandler {
 	return func(w http.ResponseWriter, r *http.Request) error {
 		battleName := r.PathValue("name")
-		_, err := s.DB.GetBattle(battleName)
+		battle, err := s.DB.GetBattle(battleName)
 		if err != nil {
-			if err == db.NotFound {
-				w.WriteHeader(http.StatusNotFound)
-				return nil
-			}
 			return err
 		}
-		return s.DB.HideBattle(battleName)
-	}
-}
+		if battle == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
 
-func (s *Server) UnhideBattle() AppHandler {
-	return func(w http.ResponseWriter, r *http.Request) error {
-		battleName := r.PathValue("name")
-		_, err := s.DB.GetBattle(battleName)
+		data, err := io.ReadAll(r.Body)
 		if err != nil {
-			if err == db.NotFound {
-				w.WriteHeader(http.StatusNotFound)
-				return nil
-			}
 			return err
 		}
-		return s.DB.UnhideBattle(battleName)
+		var req ScheduleRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return err
+		}
+		err = s.DB.ScheduleBattle(battleName, req.OpensAt, req.ClosesAt)
+		if errors.Is(err, db.InvalidSchedule) {
+			WriteJSONResponse(r.Context(), w, http.StatusBadRequest, inspectError(err))
+			return nil
+		}
+		return err
 	}
 }
 
 func (s *Server) Scan() AppHandler {
 	return func(w http.ResponseWriter, r *http.Request) error {
-		fsc := scanner.FSScanner{Fsys: s.BattlesFsys}
-		battles, err := scanner.GetAllBattles(fsc.GetBattleNames, fsc.GetBattle)
+		reports, err := s.ScanBattles()
 		if err != nil {
 			return err
 		}
-		var errs []error
-		for _, b := range battles {
-			slog.Info("updating", "battle", b.Name)
-			if err := s.DB.UpdateBattle(b); err != nil {
-				slog.Error("could not update battle", "err", err)
-				errs = append(errs, err)
-			}
+		WriteJSONResponse(r.Context(), w, http.StatusOK, reports)
+		return nil
+	}
+}
 
+// ScanBattles reads all battles from BattlesFsys and updates them in the
+// database. A battle which cannot be read or updated is logged and reported
+// with its error, an error is only returned if BattlesFsys cannot be read.
+func (s *Server) ScanBattles() ([]db.ScanReport, error) {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+
+	fsc := scanner.FSScanner{Fsys: s.BattlesFsys}
+	battles, failed, err := scanner.GetAllBattles(fsc.GetBattleNames, fsc.GetBattle)
+	if err != nil {
+		return nil, err
+	}
+	var reports []db.ScanReport
+	for _, f := range failed {
+		slog.Error("could not read battle", "battle", f.Name, "err", f.Err)
+		reports = append(reports, db.ScanReport{Battle: f.Name, Error: f.Err.Error()})
+	}
+	for _, b := range battles {
+		slog.Info("updating", "battle", b.Name)
+		report, err := s.DB.UpdateBattle(b)
+		if err != nil {
+			slog.Error("could not update battle", "battle", b.Name, "err", err)
+			reports = append(reports, db.ScanReport{Battle: b.Name, Error: err.Error()})
+			continue
 		}
-		if len(errs) > 0 {
-			return errors.Join(errs...)
+		if len(report.Renamed) > 0 || len(report.Added) > 0 || len(report.Removed) > 0 {
+			slog.Info("entries changed", "battle", b.Name,
+				"renamed", report.Renamed, "added", report.Added, "removed", report.Removed)
 		}
-		return nil
+		reports = append(reports, report)
 	}
+	return reports, nil
 }
 
 // BattleDataResponse .
@@ -673,7 +990,7 @@ func (s *Server) GetBattleData() AppHandler {
 			return err
 		}
 
-		sumScores := db.SumScores(allVotes)
+		sumScores := battle.Tally(allVotes)
 		battle.Entries.SortByScore(sumScores)
 		WriteJSONResponse(ctx, w, http.StatusOK,
 			BattleDataResponse{
@@ -714,6 +1031,11 @@ func (s *Server) battleEvents() AppHandler {
 			return nil
 		}
 
+		voters, err := s.DB.CountVoters(battleName)
+		if err != nil {
+			return err
+		}
+
 		ws, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			log.Println(err)
@@ -721,6 +1043,9 @@ func (s *Server) battleEvents() AppHandler {
 		}
 		defer ws.Close()
 
+		evs, unsubscribe := s.DB.Events.Subscribe(battleName)
+		defer unsubscribe()
+
 		ws.SetPongHandler(func(string) error {
 			ws.SetReadDeadline(time.Now().Add(pongWait))
 			return nil
@@ -759,6 +1084,34 @@ func (s *Server) battleEvents() AppHandler {
 
 		})
 
+		grp.Go(func() error {
+			writeEvent := func(ev events.Event) error {
+				ws.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := ws.WriteJSON(ev); err != nil {
+					slog.Warn("write event", "err", err)
+					return err
+				}
+				return nil
+			}
+			if err := writeEvent(events.Event{
+				Type:   events.VoterCount,
+				Battle: battleName,
+				Voters: voters,
+			}); err != nil {
+				return err
+			}
+			for {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case ev := <-evs:
+					if err := writeEvent(ev); err != nil {
+						return err
+					}
+				}
+			}
+		})
+
 		if err := grp.Wait(); err != nil {
 			return fmt.Errorf("group: %w", err)
 		}
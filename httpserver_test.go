@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsPreview(t *testing.T) {
+	tests := []struct {
+		method string
+		rng    string
+		want   bool
+	}{
+		{http.MethodGet, "", false},
+		{http.MethodHead, "", true},
+		{http.MethodGet, "bytes=0-1", true},
+		{http.MethodGet, "bytes=0-65535", true},
+		{http.MethodGet, "bytes=0-", true},
+		{http.MethodGet, "bytes=1000-2000", false},
+		{http.MethodGet, "bytes=0-1,5-10", false},
+		{http.MethodGet, "bytes=-500", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/dl/b/e", nil)
+		if tt.rng != "" {
+			r.Header.Set("Range", tt.rng)
+		}
+		if got := isPreview(r); got != tt.want {
+			t.Errorf("%s %q: isPreview = %v, want %v", tt.method, tt.rng, got, tt.want)
+		}
+	}
+}
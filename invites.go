@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/some-programs/battlr/assets"
+	"github.com/some-programs/battlr/pkg/db"
+)
+
+var errInviteRequired = errors.New("an invite is required to vote")
+
+var inviteTmpl = template.Must(template.New("base.html").
+	Funcs(template.FuncMap{
+		"static": assets.StaticHashFS.HashName,
+	}).
+	ParseFS(assets.TemplateFS, "template/base.html", "template/battle-invite.html"))
+
+// renderInvite asks the voter to confirm redeeming the invite token.
+func (s *Server) renderInvite(w http.ResponseWriter, battle db.Battle, token string) error {
+	templateData := struct {
+		Title  string
+		Battle db.Battle
+		Token  string
+	}{
+		Title:  battle.DisplayName(),
+		Battle: battle,
+		Token:  token,
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := inviteTmpl.Execute(w, &templateData); err != nil {
+		slog.Info("error", "err", err)
+		return err
+	}
+	return nil
+}
+
+// AcceptInvite redeems the invite token confirmed on the invite page.
+func (s *Server) AcceptInvite() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+		battle, err := s.DB.GetBattle(r.PathValue("name"))
+		if err != nil {
+			return err
+		}
+		if battle == nil || !battle.InviteOnly {
+			return db.NotFound
+		}
+		voteURL := "/battles/vote/" + battle.Name + "/"
+		if _, err := s.DB.RedeemInvite(battle.Name, r.PostFormValue("token"), getClientID(ctx)); err != nil {
+			switch {
+			case errors.Is(err, db.InvalidInvite):
+				return s.ErrorPage(ctx, w, r, "the invite is not valid or was already used", voteURL)
+			case errors.Is(err, db.DuplicateInvite):
+				return s.ErrorPage(ctx, w, r, "you already joined the battle with another invite", voteURL)
+			}
+			return err
+		}
+		http.Redirect(w, r, voteURL, http.StatusSeeOther)
+		return nil
+	}
+}
+
+// voterID returns the voter id of the client in the battle. In invite only
+// battles it is the voter id of the invite the client redeemed, "" if the
+// client has not redeemed one.
+func (s *Server) voterID(ctx context.Context, battle db.Battle) (string, error) {
+	clientID := getClientID(ctx)
+	if !battle.InviteOnly {
+		return clientID, nil
+	}
+	invite, err := s.DB.GetInviteByClient(battle.Name, clientID)
+	if err != nil || invite == nil {
+		return "", err
+	}
+	return invite.VoterID(), nil
+}
+
+// ballotVoter returns the voter id the client votes with in the battle. If
+// the client is not allowed to vote the error response is written and "" is
+// returned.
+func (s *Server) ballotVoter(w http.ResponseWriter, r *http.Request, battle db.Battle) (string, error) {
+	ctx := r.Context()
+	if !battle.InviteOnly && s.accountRequired(r) {
+		WriteJSONResponse(ctx, w, http.StatusUnauthorized, inspectError(errAccountRequired))
+		return "", nil
+	}
+	voterID, err := s.voterID(ctx, battle)
+	if err != nil {
+		return "", err
+	}
+	if voterID == "" {
+		WriteJSONResponse(ctx, w, http.StatusForbidden, inspectError(errInviteRequired))
+	}
+	return voterID, nil
+}
+
+// InviteRequest issues Count single-use tokens and a personal token for each
+// of Names.
+type InviteRequest struct {
+	Count int      `json:"count"`
+	Names []string `json:"names"`
+}
+
+func (s *Server) CreateInvites() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		var req InviteRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			WriteJSONResponse(r.Context(), w, http.StatusBadRequest, inspectError(err))
+			return nil
+		}
+		if req.Count < 0 || req.Count+len(req.Names) == 0 || req.Count+len(req.Names) > 1000 {
+			WriteJSONResponse(r.Context(), w, http.StatusBadRequest, inspectError(errors.New("between 1 and 1000 invites can be created at once")))
+			return nil
+		}
+		invites, err := s.DB.CreateInvites(r.PathValue("name"), req.Count, req.Names)
+		if errors.Is(err, db.NotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		WriteJSONResponse(r.Context(), w, http.StatusOK, invites)
+		return nil
+	}
+}
+
+func (s *Server) GetInvites() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		invites, err := s.DB.GetInvites(r.PathValue("name"))
+		if err != nil {
+			return err
+		}
+		if invites == nil {
+			invites = []db.Invite{}
+		}
+		WriteJSONResponse(r.Context(), w, http.StatusOK, invites)
+		return nil
+	}
+}
+
+func (s *Server) DeleteInvite() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		err := s.DB.DeleteInvite(r.PathValue("name"), r.PathValue("id"))
+		if errors.Is(err, db.NotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		return err
+	}
+}
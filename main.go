@@ -1,19 +1,25 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"log/slog"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/arl/statsviz"
 	"github.com/peterbourgon/ff/v3"
 	"github.com/some-programs/battlr/pkg/db"
-	"github.com/some-programs/battlr/pkg/scanner"
+	"github.com/some-programs/battlr/pkg/events"
 	bolt "go.etcd.io/bbolt"
 )
 
+// schedulerInterval is how often scheduled battle openings and closings are
+// checked.
+const schedulerInterval = 5 * time.Second
+
 type Flags struct {
 	APIKey           string
 	DB               string
@@ -23,6 +29,12 @@ type Flags struct {
 	FullResultsOrder bool
 	Config           string
 	Listen           string
+	Watch            bool
+	WatchInterval    time.Duration
+	WatchDebounce    time.Duration
+	MaxUploadSize    int64
+	RequireAccount   bool
+	TrustProxy       bool
 }
 
 func (f *Flags) Register(fs *flag.FlagSet) {
@@ -34,6 +46,12 @@ func (f *Flags) Register(fs *flag.FlagSet) {
 	fs.BoolVar(&f.FullResultsOrder, "full_results_order", false, "show full ordered results")
 	fs.StringVar(&f.Listen, "listen", ":8899", "http server listener")
 	fs.StringVar(&f.Config, "config", "", "Config file")
+	fs.BoolVar(&f.Watch, "watch", false, "rescan battles when files in dir change")
+	fs.DurationVar(&f.WatchInterval, "watch_interval", 5*time.Second, "how often dir is polled for changes")
+	fs.DurationVar(&f.WatchDebounce, "watch_debounce", 30*time.Second, "how long dir must be unchanged before a rescan")
+	fs.Int64Var(&f.MaxUploadSize, "max_upload_size", 200<<20, "largest accepted entry upload in bytes")
+	fs.BoolVar(&f.RequireAccount, "require_account", false, "only allow logged in users to vote")
+	fs.BoolVar(&f.TrustProxy, "trust_proxy", false, "take client addresses from the X-Forwarded-For header")
 }
 
 func main() {
@@ -53,6 +71,10 @@ func main() {
 
 		os.Exit(1)
 	}
+	if flags.WatchInterval <= 0 {
+		slog.Error("watch_interval must be positive", "watch_interval", flags.WatchInterval)
+		os.Exit(1)
+	}
 
 	{
 		f := flags
@@ -68,30 +90,50 @@ func main() {
 	}
 	defer boltdb.Close()
 
-	db := &db.DB{BoltDB: boltdb}
+	db := &db.DB{BoltDB: boltdb, Events: events.NewBus()}
 
 	rootFsys := os.DirFS(flags.Dir)
 	statsviz.RegisterDefault()
 
-	fsc := scanner.FSScanner{Fsys: rootFsys}
-	battles, err := scanner.GetAllBattles(fsc.GetBattleNames, fsc.GetBattle)
-	if err != nil {
-		slog.Error("error reading battles from directory", "dir", flags.Dir, "err", err)
-		os.Exit(1)
-	}
-	for _, b := range battles {
-		if err := db.UpdateBattle(b); err != nil {
-			slog.Error("could not update battle", "err", err)
-		}
-	}
 	server := &Server{
 		DB: db,
 		ServerConfig: ServerConfig{
 			Unrestricted:     flags.Unrestricted,
 			ShowScores:       flags.ShowScores,
 			FullResultsOrder: flags.FullResultsOrder,
+			MaxUploadSize:    flags.MaxUploadSize,
+			RequireAccount:   flags.RequireAccount,
+			TrustProxy:       flags.TrustProxy,
 		},
 		BattlesFsys: rootFsys,
+		BattlesDir:  flags.Dir,
+	}
+	if _, err := server.ScanBattles(); err != nil {
+		slog.Error("error reading battles from directory", "dir", flags.Dir, "err", err)
+		os.Exit(1)
+	}
+
+	go func() {
+		if err := server.RunScheduler(context.Background(), schedulerInterval); err != nil {
+			slog.Error("scheduler stopped", "err", err)
+		}
+	}()
+
+	if flags.Watch {
+		watcher := &DirWatcher{
+			Fsys:     rootFsys,
+			Interval: flags.WatchInterval,
+			Debounce: flags.WatchDebounce,
+			OnChange: func() error {
+				_, err := server.ScanBattles()
+				return err
+			},
+		}
+		go func() {
+			if err := watcher.Run(context.Background()); err != nil {
+				slog.Error("watcher stopped", "err", err)
+			}
+		}()
 	}
 	server.RegisterHandlers(http.DefaultServeMux, flags.APIKey, rootFsys)
 
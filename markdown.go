@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"log/slog"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdown is configured without the unsafe renderer option so raw HTML in
+// battle descriptions is not passed through.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.Linkify, extension.Strikethrough),
+)
+
+func renderMarkdown(source string) template.HTML {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		slog.Error("render markdown", "err", err)
+		return template.HTML(template.HTMLEscapeString(source))
+	}
+	return template.HTML(buf.String())
+}
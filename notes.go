@@ -0,0 +1,141 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/some-programs/battlr/pkg/db"
+)
+
+// NoteRequest sets the private note of the voter on an entry, an empty Note
+// removes it.
+type NoteRequest struct {
+	BattleName string `json:"battle_name"`
+	EntryID    string `json:"entry_id"`
+	Note       string `json:"note"`
+}
+
+func (s *Server) UpdateNote() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+
+		clientID := getClientID(ctx)
+		if clientID == "" {
+			return errors.New("no client id found")
+		}
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		var req NoteRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			WriteJSONResponse(ctx, w, http.StatusBadRequest, inspectError(err))
+			return nil
+		}
+
+		battle, err := s.DB.GetBattle(req.BattleName)
+		if err != nil {
+			return err
+		}
+		if battle == nil || (!s.Unrestricted && battle.Phase == db.PhaseHidden) {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+
+		notesID, err := s.ownerID(ctx, *battle)
+		if err != nil {
+			return err
+		}
+
+		err = s.DB.UpdateNote(battle.Name, req.EntryID, notesID, req.Note)
+		switch {
+		case errors.Is(err, db.NotFound):
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		case errors.Is(err, db.InvalidNote):
+			WriteJSONResponse(ctx, w, http.StatusBadRequest, inspectError(err))
+			return nil
+		case err != nil:
+			return err
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("{}"))
+		return nil
+	}
+}
+
+// DownloadNotes serves the notes of the voter as a text file once the battle
+// is closed and the authors are known.
+func (s *Server) DownloadNotes() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+
+		clientID := getClientID(ctx)
+		if clientID == "" {
+			return errors.New("no client id found")
+		}
+
+		battle, err := s.DB.GetBattle(r.PathValue("name"))
+		if err != nil {
+			return err
+		}
+		if battle == nil || (!s.Unrestricted && battle.Phase == db.PhaseHidden) {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		if !s.Unrestricted && battle.Phase != db.PhaseResults {
+			return s.ErrorPage(ctx, w, r, "notes can be downloaded after voting closes", "/battles/vote/"+battle.Name+"/")
+		}
+
+		notesID, err := s.ownerID(ctx, *battle)
+		if err != nil {
+			return err
+		}
+		notes, err := s.DB.GetNotes(battle.Name, notesID)
+		if err != nil {
+			return err
+		}
+		if notes == nil {
+			return s.ErrorPage(ctx, w, r, "you have no notes for this battle", "/battles/results/"+battle.Name+"/")
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", battle.Name+"-notes.txt"))
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, formatNotes(*battle, *notes))
+		return nil
+	}
+}
+
+// ownerID returns the id the notes and uploads of the client are stored with.
+// They belong to the voter so that they follow an invite, clients which cannot
+// vote keep them by their client id.
+func (s *Server) ownerID(ctx context.Context, battle db.Battle) (string, error) {
+	voterID, err := s.voterID(ctx, battle)
+	if err != nil {
+		return "", err
+	}
+	return cmp.Or(voterID, getClientID(ctx)), nil
+}
+
+// formatNotes writes the notes in the order of the battle entries.
+func formatNotes(battle db.Battle, notes db.Notes) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Notes for %s\n", battle.DisplayName())
+	for _, e := range battle.Entries {
+		note, ok := notes.Notes[e.ID]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s — %s\n%s\n", e.Author, e.Title, strings.TrimSpace(note))
+	}
+	return b.String()
+}
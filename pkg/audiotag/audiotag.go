@@ -0,0 +1,122 @@
+// Package audiotag reads artist and title information embedded in audio
+// files.
+//
+// Supported are ID3v2 tags (mp3, and wav files with an id3 chunk), Vorbis
+// comments (flac, ogg vorbis and ogg opus) and RIFF INFO chunks (wav).
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	// NotFound is returned when a file does not contain any supported tags.
+	NotFound = errors.New("no tags found")
+	// Malformed is returned when tag data is truncated or otherwise invalid.
+	Malformed = errors.New("malformed tag data")
+)
+
+// maxTagSize limits how much tag data is read into memory. Tags are mostly
+// this large because of embedded cover art.
+const maxTagSize = 64 << 20
+
+type Tags struct {
+	Artist string
+	Title  string
+}
+
+func (t Tags) IsZero() bool {
+	return t.Artist == "" && t.Title == ""
+}
+
+// Read reads tags from r, the file format is chosen by the file extension
+// ext.
+func Read(r io.Reader, ext string) (Tags, error) {
+	switch strings.ToLower(ext) {
+	case ".mp3":
+		return ReadID3v2(r)
+	case ".flac":
+		return ReadFLAC(r)
+	case ".ogg":
+		return ReadOgg(r)
+	case ".wav":
+		return ReadRIFF(r)
+	}
+	return Tags{}, NotFound
+}
+
+// parseVorbisComment parses a vorbis comment block as found in flac metadata
+// and ogg comment headers.
+func parseVorbisComment(data []byte) (Tags, error) {
+	var tags Tags
+	buf := bytes.NewReader(data)
+	var vendorLen uint32
+	if err := binary.Read(buf, binary.LittleEndian, &vendorLen); err != nil {
+		return tags, Malformed
+	}
+	if _, err := buf.Seek(int64(vendorLen), io.SeekCurrent); err != nil {
+		return tags, Malformed
+	}
+	var count uint32
+	if err := binary.Read(buf, binary.LittleEndian, &count); err != nil {
+		return tags, Malformed
+	}
+	for i := uint32(0); i < count; i++ {
+		var l uint32
+		if err := binary.Read(buf, binary.LittleEndian, &l); err != nil {
+			return tags, Malformed
+		}
+		if int64(l) > int64(buf.Len()) {
+			return tags, Malformed
+		}
+		comment := make([]byte, l)
+		if _, err := io.ReadFull(buf, comment); err != nil {
+			return tags, Malformed
+		}
+		key, value, ok := strings.Cut(string(comment), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToUpper(key) {
+		case "ARTIST":
+			if tags.Artist == "" {
+				tags.Artist = value
+			}
+		case "TITLE":
+			if tags.Title == "" {
+				tags.Title = value
+			}
+		}
+	}
+	if tags.IsZero() {
+		return tags, NotFound
+	}
+	return tags, nil
+}
+
+// skip discards n bytes from r, seeking when r supports it.
+func skip(r io.Reader, n int64) error {
+	if s, ok := r.(io.Seeker); ok {
+		_, err := s.Seek(n, io.SeekCurrent)
+		return err
+	}
+	_, err := io.CopyN(io.Discard, r, n)
+	return err
+}
+
+// readChunk reads n bytes from r after checking n against maxTagSize.
+func readChunk(r io.Reader, n int64) ([]byte, error) {
+	if n < 0 || n > maxTagSize {
+		return nil, Malformed
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, Malformed
+	}
+	return data, nil
+}
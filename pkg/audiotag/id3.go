@@ -0,0 +1,211 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// ReadID3v2 reads an ID3v2.2, v2.3 or v2.4 tag from the start of r.
+func ReadID3v2(r io.Reader) (Tags, error) {
+	var header [10]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return Tags{}, NotFound
+	}
+	if string(header[:3]) != "ID3" {
+		return Tags{}, NotFound
+	}
+	version := header[3]
+	flags := header[5]
+	size, ok := synchsafe(header[6:10])
+	if !ok {
+		return Tags{}, Malformed
+	}
+	data, err := readChunk(r, int64(size))
+	if err != nil {
+		return Tags{}, err
+	}
+	return parseID3v2(version, flags, data)
+}
+
+func parseID3v2(version, flags byte, data []byte) (Tags, error) {
+	var tags Tags
+	if version < 2 || version > 4 {
+		return tags, NotFound
+	}
+	if flags&0x80 != 0 && version < 4 {
+		data = removeUnsync(data)
+	}
+	if flags&0x40 != 0 && version > 2 {
+		if len(data) < 4 {
+			return tags, Malformed
+		}
+		var extSize int
+		if version == 3 {
+			extSize = int(binary.BigEndian.Uint32(data)) + 4
+		} else {
+			s, ok := synchsafe(data[:4])
+			if !ok {
+				return tags, Malformed
+			}
+			extSize = int(s)
+		}
+		if extSize > len(data) {
+			return tags, Malformed
+		}
+		data = data[extSize:]
+	}
+
+	idLen, headerLen := 4, 10
+	artistID, titleID := "TPE1", "TIT2"
+	if version == 2 {
+		idLen, headerLen = 3, 6
+		artistID, titleID = "TP1", "TT2"
+	}
+
+	for len(data) >= headerLen {
+		id := string(data[:idLen])
+		if id[0] == 0 {
+			// padding
+			break
+		}
+		var size int
+		var frameFlags uint16
+		switch version {
+		case 2:
+			size = int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+		case 3:
+			size = int(binary.BigEndian.Uint32(data[4:8]))
+			frameFlags = binary.BigEndian.Uint16(data[8:10])
+		case 4:
+			s, ok := synchsafe(data[4:8])
+			if !ok {
+				return tags, Malformed
+			}
+			size = int(s)
+			frameFlags = binary.BigEndian.Uint16(data[8:10])
+		}
+		if size < 0 || headerLen+size > len(data) {
+			return tags, Malformed
+		}
+		frame := data[headerLen : headerLen+size]
+		data = data[headerLen+size:]
+
+		if id != artistID && id != titleID {
+			continue
+		}
+		frame, ok := frameContent(version, frameFlags, frame)
+		if !ok {
+			continue
+		}
+		value := decodeID3Text(frame)
+		switch id {
+		case artistID:
+			tags.Artist = value
+		case titleID:
+			tags.Title = value
+		}
+	}
+	if tags.IsZero() {
+		return tags, NotFound
+	}
+	return tags, nil
+}
+
+// frameContent removes the per frame encodings, ok is false for frames which
+// are compressed or encrypted.
+func frameContent(version byte, flags uint16, frame []byte) ([]byte, bool) {
+	switch version {
+	case 3:
+		if flags&0x00c0 != 0 {
+			return nil, false
+		}
+		if flags&0x0020 != 0 {
+			if len(frame) < 1 {
+				return nil, false
+			}
+			frame = frame[1:]
+		}
+	case 4:
+		if flags&0x000c != 0 {
+			return nil, false
+		}
+		if flags&0x0040 != 0 {
+			if len(frame) < 1 {
+				return nil, false
+			}
+			frame = frame[1:]
+		}
+		if flags&0x0001 != 0 {
+			if len(frame) < 4 {
+				return nil, false
+			}
+			frame = frame[4:]
+		}
+		if flags&0x0002 != 0 {
+			frame = removeUnsync(frame)
+		}
+	}
+	return frame, true
+}
+
+// decodeID3Text decodes a text information frame, only the first value of
+// multi value frames is returned.
+func decodeID3Text(frame []byte) string {
+	if len(frame) == 0 {
+		return ""
+	}
+	encoding, text := frame[0], frame[1:]
+	var s string
+	switch encoding {
+	case 0:
+		runes := make([]rune, len(text))
+		for i, b := range text {
+			runes[i] = rune(b)
+		}
+		s = string(runes)
+	case 1, 2:
+		order := binary.ByteOrder(binary.BigEndian)
+		if encoding == 1 && len(text) >= 2 {
+			switch {
+			case text[0] == 0xff && text[1] == 0xfe:
+				order = binary.LittleEndian
+				text = text[2:]
+			case text[0] == 0xfe && text[1] == 0xff:
+				text = text[2:]
+			}
+		}
+		u := make([]uint16, 0, len(text)/2)
+		for i := 0; i+1 < len(text); i += 2 {
+			c := order.Uint16(text[i:])
+			if c == 0 {
+				break
+			}
+			u = append(u, c)
+		}
+		s = string(utf16.Decode(u))
+	default:
+		s = string(text)
+	}
+	s, _, _ = strings.Cut(s, "\x00")
+	return strings.TrimSpace(s)
+}
+
+func synchsafe(b []byte) (uint32, bool) {
+	var v uint32
+	for _, c := range b {
+		if c&0x80 != 0 {
+			return 0, false
+		}
+		v = v<<7 | uint32(c)
+	}
+	return v, true
+}
+
+// removeUnsync reverses the ID3 unsynchronisation scheme, 0xff 0x00 becomes
+// 0xff.
+func removeUnsync(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte{0xff, 0x00}, []byte{0xff})
+}
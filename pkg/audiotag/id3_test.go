@@ -0,0 +1,118 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func synchsafeBytes(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+}
+
+// id3v23Frame encodes an ID3v2.3 text frame.
+func id3v23Frame(id string, text []byte) []byte {
+	b := []byte(id)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(text)))
+	b = append(b, 0, 0)
+	return append(b, text...)
+}
+
+func id3Tag(version byte, frames ...[]byte) []byte {
+	var data []byte
+	for _, f := range frames {
+		data = append(data, f...)
+	}
+	// padding
+	data = append(data, make([]byte, 10)...)
+	b := []byte{'I', 'D', '3', version, 0, 0}
+	b = append(b, synchsafeBytes(len(data))...)
+	return append(b, data...)
+}
+
+func TestReadID3v2(t *testing.T) {
+	v24Frame := func(id string, text []byte) []byte {
+		b := append([]byte(id), synchsafeBytes(len(text))...)
+		b = append(b, 0, 0)
+		return append(b, text...)
+	}
+	v22Frame := func(id string, text []byte) []byte {
+		n := len(text)
+		return append([]byte{id[0], id[1], id[2], byte(n >> 16), byte(n >> 8), byte(n)}, text...)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want Tags
+		err  error
+	}{
+		{
+			name: "v2.3 latin1",
+			data: id3Tag(3, id3v23Frame("TPE1", []byte("\x00Artist")), id3v23Frame("TIT2", []byte("\x00Title\x00"))),
+			want: Tags{Artist: "Artist", Title: "Title"},
+		},
+		{
+			name: "v2.3 utf-16 with bom",
+			data: id3Tag(3, id3v23Frame("TIT2", []byte("\x01\xff\xfeT\x00\xe4\x00\x00\x00"))),
+			want: Tags{Title: "Tä"},
+		},
+		{
+			name: "v2.4 utf-8",
+			data: id3Tag(4, v24Frame("TPE1", []byte("\x03Ärtist")), v24Frame("TIT2", []byte("\x03Title"))),
+			want: Tags{Artist: "Ärtist", Title: "Title"},
+		},
+		{
+			name: "v2.2",
+			data: id3Tag(2, v22Frame("TP1", []byte("\x00Artist")), v22Frame("TT2", []byte("\x00Title"))),
+			want: Tags{Artist: "Artist", Title: "Title"},
+		},
+		{
+			name: "frame larger than the tag",
+			data: id3Tag(3, append([]byte("TIT2\x00\x00\x01\x00\x00\x00"), "\x00Title"...)),
+			err:  Malformed,
+		},
+		{
+			name: "no text frames",
+			data: id3Tag(3, id3v23Frame("TXXX", []byte("\x00x"))),
+			err:  NotFound,
+		},
+		{
+			name: "truncated tag",
+			data: id3Tag(3, id3v23Frame("TIT2", []byte("\x00Title")))[:15],
+			err:  Malformed,
+		},
+		{
+			name: "not id3",
+			data: []byte("RIFF0000WAVE"),
+			err:  NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadID3v2(bytes.NewReader(tt.data))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("tags = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadID3v2NoPanic(t *testing.T) {
+	for _, version := range []byte{2, 3, 4} {
+		for _, flags := range []byte{0, 0x40, 0x80} {
+			tag := id3Tag(version, id3v23Frame("TPE1", []byte("\x01\xff")), id3v23Frame("TIT2", []byte("\x00Title")))
+			tag[5] = flags
+			for n := range len(tag) {
+				ReadID3v2(bytes.NewReader(tag[:n]))
+			}
+			// the frames are cut while the tag size would allow more.
+			for n := 10; n < len(tag); n++ {
+				parseID3v2(version, flags, bytes.Clone(tag[10:n]))
+			}
+		}
+	}
+}
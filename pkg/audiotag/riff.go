@@ -0,0 +1,94 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+)
+
+// ReadRIFF reads the INFO list chunk, or an embedded ID3v2 chunk, of a RIFF
+// WAVE file. INFO values win over ID3 values when both are present.
+func ReadRIFF(r io.Reader) (Tags, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return Tags{}, NotFound
+	}
+	if string(header[:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return Tags{}, NotFound
+	}
+
+	var info, id3 Tags
+chunks:
+	for {
+		var chunkHeader [8]byte
+		if _, err := io.ReadFull(r, chunkHeader[:]); err != nil {
+			break chunks
+		}
+		id := string(chunkHeader[:4])
+		size := int64(binary.LittleEndian.Uint32(chunkHeader[4:8]))
+		padded := size + size%2
+
+		switch id {
+		case "LIST":
+			data, err := readChunk(r, padded)
+			if err != nil {
+				return Tags{}, err
+			}
+			// the declared size can be smaller than the list type or, in a
+			// truncated file, larger than what was read.
+			if size >= 4 && len(data) >= 4 && string(data[:4]) == "INFO" {
+				info = parseRIFFInfo(data[4:min(size, int64(len(data)))])
+			}
+		case "id3 ", "ID3 ":
+			data, err := readChunk(r, padded)
+			if err != nil {
+				return Tags{}, err
+			}
+			if t, err := ReadID3v2(bytes.NewReader(data)); err == nil {
+				id3 = t
+			}
+		default:
+			if err := skip(r, padded); err != nil {
+				break chunks
+			}
+		}
+	}
+
+	tags := info
+	if tags.Artist == "" {
+		tags.Artist = id3.Artist
+	}
+	if tags.Title == "" {
+		tags.Title = id3.Title
+	}
+	if tags.IsZero() {
+		return tags, NotFound
+	}
+	return tags, nil
+}
+
+func parseRIFFInfo(data []byte) Tags {
+	var tags Tags
+	for len(data) >= 8 {
+		id := string(data[:4])
+		size := int(binary.LittleEndian.Uint32(data[4:8]))
+		if size < 0 || 8+size > len(data) {
+			break
+		}
+		value, _, _ := strings.Cut(string(data[8:8+size]), "\x00")
+		value = strings.TrimSpace(value)
+		switch id {
+		case "IART":
+			tags.Artist = value
+		case "INAM":
+			tags.Title = value
+		}
+		next := 8 + size + size%2
+		if next > len(data) {
+			break
+		}
+		data = data[next:]
+	}
+	return tags
+}
@@ -0,0 +1,129 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// riffChunk encodes a chunk with a declared size, data is written as given
+// so that the size can disagree with it.
+func riffChunk(id string, size uint32, data []byte) []byte {
+	b := []byte(id)
+	b = binary.LittleEndian.AppendUint32(b, size)
+	return append(b, data...)
+}
+
+// infoList encodes a LIST/INFO chunk holding the sub chunks.
+func infoList(subChunks ...[]byte) []byte {
+	data := []byte("INFO")
+	for _, c := range subChunks {
+		data = append(data, c...)
+	}
+	return riffChunk("LIST", uint32(len(data)), data)
+}
+
+func infoValue(id, value string) []byte {
+	data := []byte(value + "\x00")
+	size := uint32(len(data))
+	if len(data)%2 == 1 {
+		data = append(data, 0)
+	}
+	return riffChunk(id, size, data)
+}
+
+func riffFile(chunks ...[]byte) []byte {
+	body := []byte("WAVE")
+	for _, c := range chunks {
+		body = append(body, c...)
+	}
+	return append(riffChunk("RIFF", uint32(len(body)), nil), body...)
+}
+
+func TestReadRIFF(t *testing.T) {
+	fmtChunk := riffChunk("fmt ", 16, make([]byte, 16))
+	dataChunk := riffChunk("data", 4, []byte{1, 2, 3, 4})
+
+	tests := []struct {
+		name string
+		data []byte
+		want Tags
+		err  error
+	}{
+		{
+			name: "info",
+			data: riffFile(fmtChunk, infoList(infoValue("IART", "Artist"), infoValue("INAM", "Title")), dataChunk),
+			want: Tags{Artist: "Artist", Title: "Title"},
+		},
+		{
+			name: "odd sized values",
+			data: riffFile(infoList(infoValue("IART", "Odd"), infoValue("INAM", "Even")), dataChunk),
+			want: Tags{Artist: "Odd", Title: "Even"},
+		},
+		{
+			name: "odd sized chunk before info",
+			data: riffFile(riffChunk("junk", 3, []byte{1, 2, 3, 0}), infoList(infoValue("INAM", "Title"))),
+			want: Tags{Title: "Title"},
+		},
+		{
+			name: "info list smaller than its type",
+			data: riffFile(riffChunk("LIST", 3, []byte("INFO"))),
+			err:  NotFound,
+		},
+		{
+			name: "empty info list",
+			data: riffFile(riffChunk("LIST", 0, nil), dataChunk),
+			err:  NotFound,
+		},
+		{
+			name: "truncated info list",
+			data: riffFile(riffChunk("LIST", 100, []byte("INFOIART"))),
+			err:  Malformed,
+		},
+		{
+			name: "info value larger than the list",
+			data: riffFile(infoList(riffChunk("IART", 100, []byte("Artist\x00\x00")))),
+			err:  NotFound,
+		},
+		{
+			name: "truncated chunk header",
+			data: append(riffFile(), []byte("LIS")...),
+			err:  NotFound,
+		},
+		{
+			name: "truncated file header",
+			data: []byte("RIFF\x00\x00"),
+			err:  NotFound,
+		},
+		{
+			name: "not a wave file",
+			data: append([]byte("RIFF\x04\x00\x00\x00AVI "), infoList(infoValue("INAM", "Title"))...),
+			err:  NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadRIFF(bytes.NewReader(tt.data))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("tags = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadRIFFNoPanic(t *testing.T) {
+	file := riffFile(infoList(infoValue("IART", "Artist"), infoValue("INAM", "Title")))
+	// every truncation and every declared list size must be handled.
+	for n := range len(file) {
+		ReadRIFF(bytes.NewReader(file[:n]))
+	}
+	for size := range uint32(40) {
+		f := bytes.Clone(file)
+		binary.LittleEndian.PutUint32(f[16:20], size)
+		ReadRIFF(bytes.NewReader(f))
+	}
+}
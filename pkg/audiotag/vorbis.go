@@ -0,0 +1,119 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+const (
+	flacBlockVorbisComment = 4
+
+	// maxOggPages limits how far into an ogg stream the comment header is
+	// searched for.
+	maxOggPages = 64
+)
+
+// ReadFLAC reads the vorbis comment metadata block of a flac file.
+func ReadFLAC(r io.Reader) (Tags, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return Tags{}, NotFound
+	}
+	if string(magic[:]) != "fLaC" {
+		return Tags{}, NotFound
+	}
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return Tags{}, NotFound
+		}
+		last := header[0]&0x80 != 0
+		blockType := header[0] & 0x7f
+		length := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+		if blockType == flacBlockVorbisComment {
+			data, err := readChunk(r, length)
+			if err != nil {
+				return Tags{}, err
+			}
+			return parseVorbisComment(data)
+		}
+		if last {
+			return Tags{}, NotFound
+		}
+		if err := skip(r, length); err != nil {
+			return Tags{}, Malformed
+		}
+	}
+}
+
+// ReadOgg reads the comment header of the first logical stream in an ogg
+// vorbis or ogg opus file.
+func ReadOgg(r io.Reader) (Tags, error) {
+	var (
+		serial  uint32
+		packets [][]byte
+		packet  []byte
+	)
+	for page := 0; page < maxOggPages; page++ {
+		var header [27]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if page == 0 {
+				return Tags{}, NotFound
+			}
+			return Tags{}, Malformed
+		}
+		if string(header[:4]) != "OggS" {
+			if page == 0 {
+				return Tags{}, NotFound
+			}
+			return Tags{}, Malformed
+		}
+		pageSerial := binary.LittleEndian.Uint32(header[14:18])
+		if page == 0 {
+			serial = pageSerial
+		}
+		segments := make([]byte, header[26])
+		if _, err := io.ReadFull(r, segments); err != nil {
+			return Tags{}, Malformed
+		}
+		if pageSerial != serial {
+			var total int64
+			for _, l := range segments {
+				total += int64(l)
+			}
+			if err := skip(r, total); err != nil {
+				return Tags{}, Malformed
+			}
+			continue
+		}
+		for _, l := range segments {
+			data, err := readChunk(r, int64(l))
+			if err != nil {
+				return Tags{}, err
+			}
+			packet = append(packet, data...)
+			if len(packet) > maxTagSize {
+				return Tags{}, Malformed
+			}
+			if l < 255 {
+				packets = append(packets, packet)
+				packet = nil
+			}
+		}
+		if len(packets) >= 2 {
+			return parseOggCommentPacket(packets[1])
+		}
+	}
+	return Tags{}, NotFound
+}
+
+func parseOggCommentPacket(packet []byte) (Tags, error) {
+	switch {
+	case bytes.HasPrefix(packet, []byte("\x03vorbis")):
+		return parseVorbisComment(packet[7:])
+	case bytes.HasPrefix(packet, []byte("OpusTags")):
+		return parseVorbisComment(packet[8:])
+	}
+	return Tags{}, NotFound
+}
@@ -0,0 +1,111 @@
+package audiotag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func vorbisComment(comments ...string) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, 6)
+	b = append(b, "vendor"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(comments)))
+	for _, c := range comments {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(c)))
+		b = append(b, c...)
+	}
+	return b
+}
+
+func flacFile(comment []byte) []byte {
+	b := []byte("fLaC")
+	// streaminfo
+	b = append(b, 0, 0, 0, 34)
+	b = append(b, make([]byte, 34)...)
+	n := len(comment)
+	b = append(b, 0x80|flacBlockVorbisComment, byte(n>>16), byte(n>>8), byte(n))
+	return append(b, comment...)
+}
+
+// oggPage encodes a page of the stream holding the packets, a packet must
+// be shorter than 255 bytes.
+func oggPage(serial uint32, packets ...[]byte) []byte {
+	b := []byte("OggS")
+	b = append(b, make([]byte, 10)...)
+	b = binary.LittleEndian.AppendUint32(b, serial)
+	b = append(b, make([]byte, 8)...)
+	b = append(b, byte(len(packets)))
+	for _, p := range packets {
+		b = append(b, byte(len(p)))
+	}
+	for _, p := range packets {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func TestReadFLAC(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want Tags
+		err  error
+	}{
+		{
+			name: "comment",
+			data: flacFile(vorbisComment("ARTIST=Artist", "title=Title", "ARTIST=Second")),
+			want: Tags{Artist: "Artist", Title: "Title"},
+		},
+		{
+			name: "comment longer than the block",
+			data: flacFile(append(vorbisComment("TITLE=Title")[:14], 100, 0, 0, 0, 'x')),
+			err:  Malformed,
+		},
+		{
+			name: "no comment",
+			data: append([]byte("fLaC"), 0x80, 0, 0, 0),
+			err:  NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadFLAC(bytes.NewReader(tt.data))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("tags = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadOgg(t *testing.T) {
+	comment := append([]byte("OpusTags"), vorbisComment("ARTIST=Artist", "TITLE=Title")...)
+	data := oggPage(1, []byte("OpusHead"))
+	data = append(data, oggPage(2, []byte("other stream"))...)
+	data = append(data, oggPage(1, comment)...)
+	got, err := ReadOgg(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Tags{Artist: "Artist", Title: "Title"}); got != want {
+		t.Errorf("tags = %+v, want %+v", got, want)
+	}
+}
+
+func TestVorbisNoPanic(t *testing.T) {
+	flac := flacFile(vorbisComment("ARTIST=Artist", "TITLE=Title"))
+	ogg := append(oggPage(1, []byte("\x01vorbis")), oggPage(1, append([]byte("\x03vorbis"), vorbisComment("TITLE=Title")...))...)
+	for n := range len(flac) {
+		ReadFLAC(bytes.NewReader(flac[:n]))
+	}
+	for n := range len(ogg) {
+		ReadOgg(bytes.NewReader(ogg[:n]))
+	}
+	comment := vorbisComment("ARTIST=Artist")
+	for n := range len(comment) {
+		parseVorbisComment(comment[:n])
+	}
+}
@@ -0,0 +1,347 @@
+package db
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	usersBucketName    = "users"
+	sessionsBucketName = "sessions"
+)
+
+// SessionDuration is how long a login is valid.
+const SessionDuration = 90 * 24 * time.Hour
+
+var (
+	InvalidAccount = errors.New("invalid account")
+	AccountExists  = errors.New("account already exists")
+	InvalidLogin   = errors.New("invalid username or password")
+)
+
+// User is a voter account. Votes, comments, notes and uploads made while
+// logged in belong to VoterID instead of the browser cookie.
+type User struct {
+	Name         string    `yaml:"name"`
+	PasswordHash []byte    `yaml:"password_hash"`
+	CreatedAt    time.Time `yaml:"created_at"`
+}
+
+// UserKey normalizes a username, usernames are case-insensitive.
+func UserKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// VoterID is the voter id of the account.
+func (u User) VoterID() string {
+	return "user:" + UserKey(u.Name)
+}
+
+// Session is a login, the token identifying it is only stored as a hash.
+type Session struct {
+	Username  string    `yaml:"username"`
+	CreatedAt time.Time `yaml:"created_at"`
+	ExpiresAt time.Time `yaml:"expires_at"`
+}
+
+func validateUsername(name string) error {
+	if n := len(name); n < 2 || n > 32 {
+		return fmt.Errorf("%w: username must be 2-32 characters", InvalidAccount)
+	}
+	for _, r := range name {
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_.", r)) {
+			return fmt.Errorf("%w: username can only contain letters, digits, '-', '_' and '.'", InvalidAccount)
+		}
+	}
+	return nil
+}
+
+func validatePassword(password string) error {
+	// bcrypt only uses the first 72 bytes.
+	if n := len(password); n < 8 || n > 72 {
+		return fmt.Errorf("%w: password must be 8-72 bytes", InvalidAccount)
+	}
+	return nil
+}
+
+// CreateUser registers a new account.
+func (db *DB) CreateUser(name string, password string) (*User, error) {
+	name = strings.TrimSpace(name)
+	if err := validateUsername(name); err != nil {
+		return nil, err
+	}
+	if err := validatePassword(password); err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	user := User{
+		Name:         name,
+		PasswordHash: hash,
+		CreatedAt:    time.Now(),
+	}
+	err = db.BoltDB.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(usersBucketName))
+		if err != nil {
+			return err
+		}
+		if bucket.Get([]byte(UserKey(name))) != nil {
+			return fmt.Errorf("%w: %s", AccountExists, name)
+		}
+		return storeYaml(bucket, []byte(UserKey(name)), user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUser returns the account with the username, nil if it does not exist.
+func (db *DB) GetUser(name string) (*User, error) {
+	var user *User
+	err := db.BoltDB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(usersBucketName))
+		if bucket == nil {
+			return nil
+		}
+		var err error
+		user, err = retreiveYaml[User](bucket, []byte(UserKey(name)))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Authenticate checks the password of an account, InvalidLogin is returned
+// if the account does not exist or the password is wrong.
+func (db *DB) Authenticate(name string, password string) (*User, error) {
+	user, err := db.GetUser(name)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, InvalidLogin
+	}
+	if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password)); err != nil {
+		return nil, InvalidLogin
+	}
+	return user, nil
+}
+
+func sessionKey(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return []byte(hex.EncodeToString(sum[:]))
+}
+
+// CreateSession logs the user in and returns the session token.
+func (db *DB) CreateSession(user User) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	now := time.Now()
+	session := Session{
+		Username:  UserKey(user.Name),
+		CreatedAt: now,
+		ExpiresAt: now.Add(SessionDuration),
+	}
+	err := db.BoltDB.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(sessionsBucketName))
+		if err != nil {
+			return err
+		}
+		// expired sessions are cleaned up on login.
+		var expired [][]byte
+		if err := bucket.ForEach(func(k, v []byte) error {
+			s, err := retreiveYaml[Session](bucket, k)
+			if err != nil {
+				return err
+			}
+			if now.After(s.ExpiresAt) {
+				expired = append(expired, k)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return storeYaml(bucket, sessionKey(token), session)
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetSessionUser returns the user logged in with the session token, nil if
+// the session does not exist or has expired.
+func (db *DB) GetSessionUser(token string) (*User, error) {
+	var user *User
+	err := db.BoltDB.View(func(tx *bolt.Tx) error {
+		sessions := tx.Bucket([]byte(sessionsBucketName))
+		users := tx.Bucket([]byte(usersBucketName))
+		if sessions == nil || users == nil {
+			return nil
+		}
+		session, err := retreiveYaml[Session](sessions, sessionKey(token))
+		if err != nil || session == nil || time.Now().After(session.ExpiresAt) {
+			return err
+		}
+		user, err = retreiveYaml[User](users, []byte(session.Username))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// DeleteSession logs out the session.
+func (db *DB) DeleteSession(token string) error {
+	return db.BoltDB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(sessionsBucketName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete(sessionKey(token))
+	})
+}
+
+// LinkVoter moves the ballots, notes, uploads, roles and redeemed invites of
+// voterID, usually a browser cookie, to the account in every battle. A ballot
+// or note the account already has in a battle is kept and the one of voterID
+// is dropped so that every person has a single ballot. Outside of PhaseVoting
+// a conflicting ballot stays with voterID together with its role and uploads,
+// so that the results of closed battles do not change.
+func (db *DB) LinkVoter(user User, voterID string) error {
+	accountID := user.VoterID()
+	if voterID == "" || voterID == accountID {
+		return nil
+	}
+	return db.BoltDB.Update(func(tx *bolt.Tx) error {
+		battlesBucket := tx.Bucket([]byte(battlesBucketName))
+		if battlesBucket == nil {
+			return nil
+		}
+		var battles []Battle
+		if err := battlesBucket.ForEach(func(k, v []byte) error {
+			battle, err := getBattle(battlesBucket, string(k))
+			if err != nil {
+				return err
+			}
+			battles = append(battles, *battle)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, battle := range battles {
+			voting := battle.Phase == PhaseVoting
+			// keep is set when the ballot of voterID stays where it is.
+			keep := false
+
+			if votesBucket := tx.Bucket(newVotesBucketKey(battle.Name)); votesBucket != nil {
+				votes, err := getVotes(votesBucket, battle.Name, voterID)
+				if err != nil {
+					return err
+				}
+				if votes != nil {
+					conflict := votesBucket.Get([]byte(accountID)) != nil
+					keep = conflict && !voting
+					if !conflict {
+						votes.VoterID = accountID
+						if err := putVotes(votesBucket, *votes); err != nil {
+							return err
+						}
+					}
+					if !keep {
+						if err := votesBucket.Delete([]byte(voterID)); err != nil {
+							return err
+						}
+					}
+				}
+			}
+
+			if notesBucket := tx.Bucket(newNotesBucketKey(battle.Name)); notesBucket != nil {
+				notes, err := retreiveYaml[Notes](notesBucket, []byte(voterID))
+				if err != nil {
+					return err
+				}
+				if notes != nil {
+					if notesBucket.Get([]byte(accountID)) == nil {
+						notes.VoterID = accountID
+						if err := storeYaml(notesBucket, []byte(accountID), *notes); err != nil {
+							return err
+						}
+					}
+					if err := notesBucket.Delete([]byte(voterID)); err != nil {
+						return err
+					}
+				}
+			}
+
+			if invitesBucket := tx.Bucket(newInvitesBucketKey(battle.Name)); invitesBucket != nil {
+				var invites []Invite
+				if err := invitesBucket.ForEach(func(k, v []byte) error {
+					invite, err := retreiveYaml[Invite](invitesBucket, k)
+					if err != nil {
+						return err
+					}
+					if i := slices.Index(invite.Clients, voterID); i >= 0 {
+						invite.Clients[i] = accountID
+						invites = append(invites, *invite)
+					}
+					return nil
+				}); err != nil {
+					return err
+				}
+				for _, invite := range invites {
+					if err := storeYaml(invitesBucket, []byte(invite.Token), invite); err != nil {
+						return err
+					}
+				}
+			}
+
+			changed := false
+			for i, e := range battle.Entries {
+				if e.UploaderID == voterID && !keep {
+					battle.Entries[i].UploaderID = accountID
+					changed = true
+				}
+			}
+			if role, ok := battle.VoterRoles[voterID]; ok && !keep {
+				if _, exists := battle.VoterRoles[accountID]; !exists {
+					battle.VoterRoles[accountID] = role
+				}
+				delete(battle.VoterRoles, voterID)
+				changed = true
+			}
+			if changed {
+				if err := putBattle(battlesBucket, battle); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
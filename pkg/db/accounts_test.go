@@ -0,0 +1,60 @@
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLinkVoterMovesBallots(t *testing.T) {
+	db := newTestDB(t)
+	user := User{Name: "Al"}
+	tests := []struct {
+		battle   string
+		closed   bool
+		conflict bool
+		want     []string
+	}{
+		{"open", false, false, []string{user.VoterID()}},
+		{"closed", true, false, []string{user.VoterID()}},
+		{"open-conflict", false, true, []string{user.VoterID()}},
+		// dropping a ballot would change the published results.
+		{"closed-conflict", true, true, []string{"cookie:al", user.VoterID()}},
+	}
+	for _, tt := range tests {
+		battle := newTestBattle(t, db, tt.battle, "a.wav", "b.wav")
+		if err := db.SetPhase(tt.battle, PhaseVoting, "test"); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.UpdateVote(tt.battle, battle.Entries[0].ID, "cookie:al", 3, BallotRequest{}); err != nil {
+			t.Fatal(err)
+		}
+		if tt.conflict {
+			if err := db.UpdateVote(tt.battle, battle.Entries[1].ID, user.VoterID(), 3, BallotRequest{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if tt.closed {
+			if err := db.SetPhase(tt.battle, PhaseResults, "test"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := db.LinkVoter(user, "cookie:al"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		votes, err := db.GetAllVotes(tt.battle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, v := range votes {
+			got = append(got, v.VoterID)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: ballots by %v, want %v", tt.battle, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package db
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const aliasesBucketName = "aliases"
+
+var InvalidAlias = errors.New("invalid alias")
+
+// hashKeyPrefix starts the keys of names without letters or digits. Other
+// keys only hold letters and digits so the two never collide.
+const hashKeyPrefix = "_"
+
+// ArtistKey normalizes an author name for comparison. Case, whitespace and
+// punctuation are ignored so "DJ A-Z", "dj_a-z" and "DJ AZ" are the same
+// artist. The key is never empty and the key of a key is the key itself.
+func ArtistKey(name string) string {
+	if isHashKey(name) {
+		return name
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() > 0 {
+		return b.String()
+	}
+	// names without letters or digits, like "!!!", are told apart by a
+	// hash which can be used in urls.
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(strings.ToLower(name)), " ")))
+	return hashKeyPrefix + hex.EncodeToString(sum[:4])
+}
+
+// isHashKey reports whether key is an ArtistKey of a name without letters or
+// digits.
+func isHashKey(key string) bool {
+	hash, ok := strings.CutPrefix(key, hashKeyPrefix)
+	if !ok || len(hash) != 8 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil && hash == strings.ToLower(hash)
+}
+
+// Aliases maps the artist keys of alternative spellings to the name of the
+// artist they belong to.
+type Aliases map[string]string
+
+// Key returns the artist key of an author name with aliases resolved.
+func (a Aliases) Key(author string) string {
+	key := ArtistKey(author)
+	if artist, ok := a[key]; ok {
+		return ArtistKey(artist)
+	}
+	return key
+}
+
+// artistName returns the artist name aliases of the artist key point to.
+func (a Aliases) artistName(key string) (string, bool) {
+	var names []string
+	for _, artist := range a {
+		if ArtistKey(artist) == key {
+			names = append(names, artist)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	return slices.Min(names), true
+}
+
+// Alias is a spelling of an artist name.
+type Alias struct {
+	Alias  string `yaml:"alias" json:"alias"`
+	Artist string `yaml:"artist" json:"artist"`
+}
+
+func (db *DB) GetAliases() (Aliases, error) {
+	aliases := make(Aliases)
+	err := db.BoltDB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(aliasesBucketName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			alias, err := retreiveYaml[Alias](bucket, k)
+			if err != nil {
+				return err
+			}
+			aliases[string(k)] = alias.Artist
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// GetAllAliases returns all aliases ordered by artist.
+func (db *DB) GetAllAliases() ([]Alias, error) {
+	var aliases []Alias
+	err := db.BoltDB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(aliasesBucketName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			alias, err := retreiveYaml[Alias](bucket, k)
+			if err != nil {
+				return err
+			}
+			aliases = append(aliases, *alias)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(aliases, func(a, b Alias) int {
+		if v := cmp.Compare(ArtistKey(a.Artist), ArtistKey(b.Artist)); v != 0 {
+			return v
+		}
+		return cmp.Compare(ArtistKey(a.Alias), ArtistKey(b.Alias))
+	})
+	return aliases, nil
+}
+
+// PutAlias makes alias a spelling of artist. Aliases of aliases are not
+// resolved so artist must not itself be an alias.
+func (db *DB) PutAlias(alias, artist string) error {
+	if strings.TrimSpace(alias) == "" || strings.TrimSpace(artist) == "" {
+		return fmt.Errorf("%w: alias and artist are required", InvalidAlias)
+	}
+	aliasKey, artistKey := ArtistKey(alias), ArtistKey(artist)
+	return db.BoltDB.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(aliasesBucketName))
+		if err != nil {
+			return err
+		}
+		if aliasKey != artistKey && bucket.Get([]byte(artistKey)) != nil {
+			return fmt.Errorf("%w: %s is an alias", InvalidAlias, artist)
+		}
+		// an artist name can be an alias of itself to fix its spelling.
+		if aliasKey != artistKey && isAliasTarget(bucket, aliasKey) {
+			return fmt.Errorf("%w: %s has aliases", InvalidAlias, alias)
+		}
+		return storeYaml(bucket, []byte(aliasKey), Alias{Alias: alias, Artist: artist})
+	})
+}
+
+// isAliasTarget reports whether any alias points to the artist key.
+func isAliasTarget(bucket *bolt.Bucket, artistKey string) bool {
+	found := false
+	bucket.ForEach(func(k, v []byte) error {
+		alias, err := retreiveYaml[Alias](bucket, k)
+		if err == nil && string(k) != artistKey && ArtistKey(alias.Artist) == artistKey {
+			found = true
+		}
+		return nil
+	})
+	return found
+}
+
+func (db *DB) DeleteAlias(alias string) error {
+	key := []byte(ArtistKey(alias))
+	return db.BoltDB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(aliasesBucketName))
+		if bucket == nil || bucket.Get(key) == nil {
+			return NotFound
+		}
+		return bucket.Delete(key)
+	})
+}
+
+// Artist is the battle history of an artist.
+type Artist struct {
+	Key     string
+	Name    string
+	Entries []ArtistEntry
+}
+
+// ArtistEntry is an entry of an artist in a battle with published results.
+type ArtistEntry struct {
+	Battle Battle
+	Entry  Entry
+	// Place is the 1-based place of the entry, 0 if it got no score.
+	Place     int
+	Score     int
+	NumVoters int
+	ClosedAt  time.Time
+}
+
+// GetArtist returns the entries of an artist in battles with published
+// results, newest first. nil is returned if the artist has no such entries.
+func (db *DB) GetArtist(name string) (*Artist, error) {
+	aliases, err := db.GetAliases()
+	if err != nil {
+		return nil, err
+	}
+	battles, err := db.GetAllBattles()
+	if err != nil {
+		return nil, err
+	}
+	artist := Artist{
+		Key: aliases.Key(name),
+	}
+	for _, battle := range battles {
+		if battle.Phase != PhaseResults || battle.Reveal.Active {
+			continue
+		}
+		if !slices.ContainsFunc(battle.Entries, func(e Entry) bool {
+			return aliases.Key(e.Author) == artist.Key
+		}) {
+			continue
+		}
+		votes, err := db.GetAllVotes(battle.Name)
+		if err != nil {
+			return nil, err
+		}
+		results := battle.Results(votes)
+		for placeIdx, place := range results.Places {
+			for _, entry := range place.Entries {
+				if aliases.Key(entry.Author) != artist.Key {
+					continue
+				}
+				ae := ArtistEntry{
+					Battle:    battle,
+					Entry:     entry,
+					Score:     place.Score,
+					NumVoters: len(votes),
+					ClosedAt:  battle.ClosedAt(),
+				}
+				if place.Placed(battle.ScoringSystem()) {
+					ae.Place = placeIdx + 1
+				}
+				artist.Entries = append(artist.Entries, ae)
+			}
+		}
+	}
+	if len(artist.Entries) == 0 {
+		return nil, nil
+	}
+	slices.SortStableFunc(artist.Entries, func(a, b ArtistEntry) int {
+		return b.ClosedAt.Compare(a.ClosedAt)
+	})
+	// the name set by an alias or the latest spelling is used.
+	if name, ok := aliases.artistName(artist.Key); ok {
+		artist.Name = name
+	} else {
+		artist.Name = artist.Entries[0].Entry.Author
+	}
+	return &artist, nil
+}
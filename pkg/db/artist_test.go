@@ -0,0 +1,81 @@
+package db
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/some-programs/battlr/pkg/scanner"
+)
+
+func TestArtistKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"DJ A-Z", "dj_a-z", true},
+		{"DJ A-Z", "DJ AZ", true},
+		{"Ünïcode", "ünïcode", true},
+		{"!!!", "! ! !", false},
+		{"!!!", "  !!! ", true},
+		{"!!!", "???", false},
+		{"!!!", "", false},
+		{"DJ A-Z", "DJ B", false},
+	}
+	for _, tt := range tests {
+		ka, kb := ArtistKey(tt.a), ArtistKey(tt.b)
+		if ka == "" || kb == "" {
+			t.Errorf("empty key for %q or %q", tt.a, tt.b)
+		}
+		if (ka == kb) != tt.same {
+			t.Errorf("ArtistKey(%q) = %q, ArtistKey(%q) = %q, same = %v", tt.a, ka, tt.b, kb, tt.same)
+		}
+	}
+	// keys of names without letters or digits are used in artist urls.
+	for _, name := range []string{"!!!", "?/#", ""} {
+		if k := ArtistKey(name); url.PathEscape(k) != k {
+			t.Errorf("ArtistKey(%q) = %q needs escaping", name, k)
+		}
+	}
+}
+
+func TestArtistKeyIdempotent(t *testing.T) {
+	for _, name := range []string{"DJ A-Z", "Ünïcode", "!!!", "?/#", "", "_e84c538e", "e84c538e"} {
+		key := ArtistKey(name)
+		if again := ArtistKey(key); again != key {
+			t.Errorf("ArtistKey(%q) = %q, ArtistKey(%q) = %q", name, key, key, again)
+		}
+	}
+	// a real artist named like the hash of another name has a different key.
+	key := ArtistKey("!!!")
+	if other := ArtistKey(key[len(hashKeyPrefix):]); other == key {
+		t.Errorf("ArtistKey(%q) = %q collides with ArtistKey(%q)", key[len(hashKeyPrefix):], other, "!!!")
+	}
+}
+
+func TestGetArtistWithoutLetters(t *testing.T) {
+	db := newTestDB(t)
+	fsBattle := scanner.Battle{
+		Name: "b",
+		Entries: []scanner.Entry{
+			{Author: "!!!", Title: "a", Filename: "a.wav", Path: "b/a.wav", Hash: "a"},
+			{Author: "DJ B", Title: "b", Filename: "b.wav", Path: "b/b.wav", Hash: "b"},
+		},
+	}
+	if _, err := db.UpdateBattle(fsBattle); err != nil {
+		t.Fatal(err)
+	}
+	for _, phase := range []Phase{PhaseVoting, PhaseResults} {
+		if err := db.SetPhase("b", phase, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the key is used in the artist urls of the results and season pages.
+	artist, err := db.GetArtist(ArtistKey("!!!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if artist == nil || len(artist.Entries) != 1 || artist.Entries[0].Entry.Author != "!!!" {
+		t.Errorf("artist = %+v, want the entry by %q", artist, "!!!")
+	}
+}
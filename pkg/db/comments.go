@@ -0,0 +1,186 @@
+package db
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// MaxCommentLength is the maximum number of characters in a comment.
+const MaxCommentLength = 2000
+
+var InvalidComment = errors.New("invalid comment")
+
+// CommentVisibility selects who can read the comments of a closed battle.
+type CommentVisibility string
+
+const (
+	// CommentsPrivate shows the comments on an entry only to its entrant, this
+	// is the default.
+	CommentsPrivate CommentVisibility = "private"
+	// CommentsPublic shows all comments on the results page.
+	CommentsPublic CommentVisibility = "public"
+)
+
+func (v CommentVisibility) Valid() bool {
+	switch v {
+	case "", CommentsPrivate, CommentsPublic:
+		return true
+	}
+	return false
+}
+
+// Comment is written feedback for the author of an entry. Comments are
+// stored in the ballot of the voter.
+type Comment struct {
+	Text      string    `yaml:"text" json:"text"`
+	UpdatedAt time.Time `yaml:"updated_at" json:"updated_at"`
+	// Hidden is set by moderators, hidden comments are not shown to anyone.
+	Hidden bool `yaml:"hidden,omitempty" json:"hidden"`
+}
+
+// EntryComment is a comment with the entry and voter it belongs to.
+type EntryComment struct {
+	EntryID string `json:"entry_id"`
+	VoterID string `json:"voter_id"`
+	Comment
+}
+
+// VisibleComments returns the comments which are not hidden keyed by entry
+// id. Comments are ordered by time and carry no voter information.
+func VisibleComments(votes []Votes) map[string][]Comment {
+	res := make(map[string][]Comment)
+	for _, v := range votes {
+		for entryID, c := range v.Comments {
+			if !c.Hidden {
+				res[entryID] = append(res[entryID], c)
+			}
+		}
+	}
+	for _, comments := range res {
+		slices.SortFunc(comments, func(a, b Comment) int {
+			if v := a.UpdatedAt.Compare(b.UpdatedAt); v != 0 {
+				return v
+			}
+			return cmp.Compare(a.Text, b.Text)
+		})
+	}
+	return res
+}
+
+// UpdateComment sets the comment of a voter on an entry, an empty text
+// removes the comment. A moderated comment stays hidden when it is edited.
+func (db *DB) UpdateComment(battleName string, entryID string, voterID string, text string) error {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) > MaxCommentLength {
+		return fmt.Errorf("%w: longer than %d characters", InvalidComment, MaxCommentLength)
+	}
+	// comments do not cast a ballot, the voter count is not changed.
+	return db.BoltDB.Update(func(tx *bolt.Tx) error {
+		battlesBucket := tx.Bucket([]byte(battlesBucketName))
+		if battlesBucket == nil {
+			return NotFound
+		}
+		battle, err := getBattle(battlesBucket, battleName)
+		if err != nil {
+			return err
+		}
+		if battle == nil {
+			return NotFound
+		}
+		if _, ok := battle.GetEntryByID(entryID); !ok {
+			return NotFound
+		}
+
+		votesBucket, err := tx.CreateBucketIfNotExists(newVotesBucketKey(battleName))
+		if err != nil {
+			return err
+		}
+		votes, err := getVotes(votesBucket, battleName, voterID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if votes == nil {
+			if text == "" {
+				return nil
+			}
+			votes = &Votes{
+				VoterID:   voterID,
+				CreatedAt: now,
+				Scores:    make(ScoreMap),
+			}
+		}
+
+		if text == "" {
+			delete(votes.Comments, entryID)
+		} else {
+			if votes.Comments == nil {
+				votes.Comments = make(map[string]Comment)
+			}
+			comment := votes.Comments[entryID]
+			comment.Text = text
+			comment.UpdatedAt = now
+			votes.Comments[entryID] = comment
+		}
+		votes.UpdatedAt = now
+
+		return putVotes(votesBucket, *votes)
+	})
+}
+
+// GetComments returns all comments of a battle including hidden ones ordered
+// by entry and time.
+func (db *DB) GetComments(battleName string) ([]EntryComment, error) {
+	votes, err := db.GetAllBallots(battleName)
+	if err != nil {
+		return nil, err
+	}
+	var comments []EntryComment
+	for _, v := range votes {
+		for entryID, c := range v.Comments {
+			comments = append(comments, EntryComment{
+				EntryID: entryID,
+				VoterID: v.VoterID,
+				Comment: c,
+			})
+		}
+	}
+	slices.SortFunc(comments, func(a, b EntryComment) int {
+		if v := cmp.Compare(a.EntryID, b.EntryID); v != 0 {
+			return v
+		}
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	})
+	return comments, nil
+}
+
+// SetCommentHidden hides or shows the comment of a voter on an entry.
+func (db *DB) SetCommentHidden(battleName string, entryID string, voterID string, hidden bool) error {
+	return db.BoltDB.Update(func(tx *bolt.Tx) error {
+		votesBucket := tx.Bucket(newVotesBucketKey(battleName))
+		if votesBucket == nil {
+			return NotFound
+		}
+		votes, err := getVotes(votesBucket, battleName, voterID)
+		if err != nil {
+			return err
+		}
+		if votes == nil {
+			return NotFound
+		}
+		comment, ok := votes.Comments[entryID]
+		if !ok {
+			return NotFound
+		}
+		comment.Hidden = hidden
+		votes.Comments[entryID] = comment
+		return putVotes(votesBucket, *votes)
+	})
+}
@@ -3,20 +3,24 @@ package db
 import (
 	"cmp"
 	"errors"
+	"fmt"
 	"log/slog"
 	"math/rand/v2"
 	"slices"
 	"time"
 
 	"github.com/rs/xid"
+	"github.com/some-programs/battlr/pkg/events"
 	"github.com/some-programs/battlr/pkg/scanner"
 	bolt "go.etcd.io/bbolt"
 	"gopkg.in/yaml.v3"
 )
 
 var (
-	NotFound     = errors.New("not found")
-	InvalidScore = errors.New("invalid score")
+	NotFound        = errors.New("not found")
+	InvalidScore    = errors.New("invalid score")
+	SelfVote        = errors.New("entrants cannot vote for their own entry")
+	InvalidSchedule = errors.New("invalid schedule")
 )
 
 const (
@@ -26,6 +30,8 @@ const (
 
 type DB struct {
 	BoltDB *bolt.DB
+	// Events receives vote and phase change events, it is optional.
+	Events *events.Bus
 }
 
 type Entries []Entry
@@ -70,43 +76,98 @@ func (e Entries) Contains(entry Entry) bool {
 	return false
 }
 
-func (e Entries) Places(scoreMap ScoreMap) Places {
+// Places orders the entries by score and groups them into places. Entries
+// with equal scores are ordered by the tie-break rules, entries which are
+// still tied share a place.
+func (e Entries) Places(scoreMap ScoreMap, rules []TieBreakRule, stats TieBreakStats) Places {
 	if len(e) == 0 {
 		return nil
 	}
 	e.SortByID()
-	e.SortByScore(scoreMap)
-
-	var topPlaces Places
-	var currentPlace Entries
+	slices.SortStableFunc(e, func(a, b Entry) int {
+		if v := cmp.Compare(scoreMap[b.ID], scoreMap[a.ID]); v != 0 {
+			return v
+		}
+		for _, rule := range rules {
+			if v := rule.Compare(a, b, stats); v != 0 {
+				return v
+			}
+		}
+		return 0
+	})
 
+	var places Places
+	// groupStart is the index in places of the first place with the current
+	// score.
+	var groupStart int
 	for i, entry := range e {
-		if i != 0 && scoreMap[e[i-1].ID] != scoreMap[entry.ID] {
-			currentPlace.SortByID()
-			currentPlace.Shuffle()
-			topPlaces = append(topPlaces, currentPlace)
-			currentPlace = []Entry{}
+		if i == 0 {
+			places = append(places, Place{Score: scoreMap[entry.ID]})
+		} else if prev := e[i-1]; scoreMap[prev.ID] != scoreMap[entry.ID] {
+			groupStart = len(places)
+			places = append(places, Place{Score: scoreMap[entry.ID]})
+		} else if rule := decidingRule(rules, prev, entry, stats); rule != "" {
+			if len(places)-1 == groupStart && places[groupStart].DecidedBy == "" {
+				places[groupStart].DecidedBy = rule
+			}
+			places = append(places, Place{Score: scoreMap[entry.ID], DecidedBy: rule})
 		}
-
-		currentPlace = append(currentPlace, entry)
-		if scoreMap[entry.ID] == 0 {
-			return topPlaces
+		last := &places[len(places)-1]
+		last.Entries = append(last.Entries, entry)
+		if stats.VotesReceived[entry.ID] > 0 {
+			last.Voted = true
 		}
-
 	}
+	for _, place := range places {
+		place.Entries.SortByName()
+	}
+	return places
+}
 
-	currentPlace.SortByName()
-	topPlaces = append(topPlaces, currentPlace)
+// Place is one or more entries sharing a position in the results.
+type Place struct {
+	Entries Entries
+	Score   int
+	// DecidedBy is the tie-break rule which separated this place from other
+	// entries with the same score. It is empty if no other entry had the same
+	// score.
+	DecidedBy TieBreakRule
+	// Voted reports whether an entry of the place is on any ballot. A
+	// participation penalty lowers the score but does not change it.
+	Voted bool
+}
+
+// Shared reports whether the entries in the place could not be separated by
+// any tie-break rule.
+func (p Place) Shared() bool {
+	return len(p.Entries) > 1
+}
 
-	return topPlaces
+type Places []Place
+
+// Placed reports whether the place is awarded. Places without votes are not,
+// unless the system ranks every entry: the last entry of a Schulze ranking
+// beats no other entry and scores 0.
+func (p Place) Placed(system ScoringSystem) bool {
+	return p.Voted || system.RanksAll()
 }
 
-type Places []Entries
+// Top returns at most n places, places which are not Placed are left out.
+func (p Places) Top(n int, system ScoringSystem) Places {
+	var res Places
+	for _, place := range p {
+		if len(res) == n || !place.Placed(system) {
+			break
+		}
+		res = append(res, place)
+	}
+	return res
+}
 
 func (p Places) All() Entries {
 	var res Entries
 	for _, place := range p {
-		res = append(res, place...)
+		res = append(res, place.Entries...)
 	}
 	return res
 }
@@ -123,15 +184,97 @@ func (p Places) Diff(entries Entries) Entries {
 }
 
 type Battle struct {
-	Name      string    `yaml:"name"`
-	Entries   Entries   `yaml:"entries"`
-	ClosedAt  time.Time `yaml:"closed_at"`
-	CreatedAt time.Time `yaml:"crated_at"`
-	Hidden    bool      `yaml:"hidden"`
+	Name        string        `yaml:"name"`
+	Title       string        `yaml:"title"`
+	Description string        `yaml:"description"`
+	Theme       string        `yaml:"theme"`
+	Scoring     ScoringConfig `yaml:"scoring"`
+	// TieBreak are the tie-break rules in order, DefaultTieBreakRules is used
+	// if it is empty.
+	TieBreak []TieBreakRule `yaml:"tie_break"`
+	Entries  Entries        `yaml:"entries"`
+	// OpensAt and ClosesAt are the scheduled times for opening and closing
+	// voting, zero values are not scheduled.
+	OpensAt  time.Time `yaml:"opens_at"`
+	ClosesAt time.Time `yaml:"closes_at"`
+	// Phase is the current lifecycle phase, Transitions records all phase
+	// changes.
+	Phase       Phase             `yaml:"phase"`
+	Transitions []PhaseTransition `yaml:"transitions"`
+	Reveal      Reveal            `yaml:"reveal"`
+	Comments    CommentVisibility `yaml:"comments"`
+	// InviteOnly only allows voting with an invite token.
+	InviteOnly bool `yaml:"invite_only"`
+	// Participation requires entrants to cast a full ballot.
+	Participation ParticipationConfig `yaml:"participation"`
+	// Roles weigh the ballots of each voter role, the ballots are tallied
+	// together if it is empty.
+	Roles RoleWeights `yaml:"roles"`
+	// VoterRoles maps voter ids to their role, voters not in it are
+	// RolePublic.
+	VoterRoles map[string]string `yaml:"voter_roles"`
+	CreatedAt  time.Time         `yaml:"crated_at"`
+}
+
+// DisplayName returns the title from the battle metadata or the battle name
+// if no title is set.
+func (d Battle) DisplayName() string {
+	if d.Title != "" {
+		return d.Title
+	}
+	return d.Name
+}
+
+// ScoringSystem returns the configured scoring system of the battle.
+func (d Battle) ScoringSystem() ScoringSystem {
+	system, err := NewScoringSystem(d.Scoring)
+	if err != nil {
+		slog.Error("invalid scoring config, using default", "battle", d.Name, "err", err)
+		system, _ = NewScoringSystem(ScoringConfig{})
+	}
+	return system
+}
+
+// Tally returns the totals of all entries using the battle scoring system.
+func (d Battle) Tally(votes []Votes) ScoreMap {
+	return d.ScoringSystem().Tally(d.Entries, votes)
+}
+
+// TieBreakRules returns the configured tie-break rules or the default rules.
+func (d Battle) TieBreakRules() []TieBreakRule {
+	if len(d.TieBreak) == 0 {
+		return DefaultTieBreakRules
+	}
+	return d.TieBreak
 }
 
 func (d Battle) IsVotingOpen() bool {
-	return !d.Hidden && d.ClosedAt.IsZero()
+	return d.Phase == PhaseVoting
+}
+
+// IsScheduledToOpen reports whether voting is scheduled to open in the
+// future.
+func (d Battle) IsScheduledToOpen() bool {
+	return !d.OpensAt.IsZero() && time.Now().Before(d.OpensAt) && d.Phase != PhaseVoting
+}
+
+// ShouldOpen reports whether a scheduled opening is due and has not been
+// applied yet. Only battles which have not been voted on yet are opened.
+func (d Battle) ShouldOpen(now time.Time) bool {
+	switch d.Phase {
+	case PhaseHidden, PhaseSubmission, PhaseListening:
+	default:
+		return false
+	}
+	return !d.OpensAt.IsZero() && !now.Before(d.OpensAt) &&
+		d.LastTransitionTo(PhaseVoting).Before(d.OpensAt)
+}
+
+// ShouldClose reports whether a scheduled closing is due. Battles opened
+// again after the closing time are left open.
+func (d Battle) ShouldClose(now time.Time) bool {
+	return d.Phase == PhaseVoting && !d.ClosesAt.IsZero() && !now.Before(d.ClosesAt) &&
+		d.LastTransitionTo(PhaseVoting).Before(d.ClosesAt)
 }
 
 func (d *Battle) GetEntryByID(id string) (Entry, bool) {
@@ -152,12 +295,72 @@ func (d *Battle) GetEntryByFilename(filename string) (Entry, bool) {
 	return Entry{}, false
 }
 
+func (d *Battle) getUnmatchedEntryByHash(hash string, matched map[string]bool) (Entry, bool) {
+	if hash == "" {
+		return Entry{}, false
+	}
+	for _, e := range d.Entries {
+		if hash == e.Hash && !matched[e.ID] {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
 type Entry struct {
 	ID        string    `yaml:"id"`
 	Title     string    `yaml:"title"`
 	Author    string    `yaml:"author"`
 	Filename  string    `yaml:"filename"`
+	Hash      string    `yaml:"hash"`
 	CreatedAt time.Time `yaml:"created_at"`
+	// SubmittedAt is the modification time of the file when the entry was
+	// first found.
+	SubmittedAt time.Time `yaml:"submitted_at"`
+	// UploaderID is the voter id of the entrant who uploaded the entry through
+	// the web, it is empty for entries added to the battle directory.
+	UploaderID string `yaml:"uploader_id"`
+	// EntrantID is the voter id of the entrant set by an admin, for entries
+	// which were not uploaded or whose entrant votes with another identity.
+	EntrantID string `yaml:"entrant_id"`
+}
+
+// inherit copies the stored identity of prev to a scanned entry. Uploaded
+// entries keep the author and title given by the entrant unless they are
+// overridden in the metadata file.
+func (e *Entry) inherit(prev Entry, overrides map[string]scanner.EntryMeta) {
+	e.ID = prev.ID
+	e.CreatedAt = prev.CreatedAt
+	if !prev.SubmittedAt.IsZero() {
+		e.SubmittedAt = prev.SubmittedAt
+	}
+	e.UploaderID = prev.UploaderID
+	e.EntrantID = prev.EntrantID
+	if prev.UploaderID != "" {
+		override := overrides[e.Filename]
+		e.Author = cmp.Or(override.Author, prev.Author)
+		e.Title = cmp.Or(override.Title, prev.Title)
+	}
+}
+
+// EntrantVoterID returns the voter id the entrant votes with, the EntrantID
+// set by an admin or else the uploader.
+func (e Entry) EntrantVoterID() string {
+	return cmp.Or(e.EntrantID, e.UploaderID)
+}
+
+// IsEntrant reports whether voterID is the entrant of the entry.
+func (e Entry) IsEntrant(voterID string) bool {
+	return voterID != "" && (voterID == e.EntrantID || voterID == e.UploaderID)
+}
+
+// SubmissionTime returns SubmittedAt, or CreatedAt for entries stored before
+// SubmittedAt was recorded.
+func (e Entry) SubmissionTime() time.Time {
+	if e.SubmittedAt.IsZero() {
+		return e.CreatedAt
+	}
+	return e.SubmittedAt
 }
 
 // ScoreMap is [entryID]score
@@ -169,9 +372,14 @@ type Votes struct {
 	CreatedAt  time.Time `yaml:"created_at"`
 	UpdatedAt  time.Time `yaml:"updated_at"`
 	Scores     ScoreMap  `yaml:"score"`
+	// Comments are keyed by entry id.
+	Comments map[string]Comment `yaml:"comments,omitempty"`
+	Meta     BallotMeta         `yaml:"meta,omitempty"`
+	// Excluded ballots are left out of the tally by an admin.
+	Excluded bool `yaml:"excluded,omitempty"`
 }
 
-// SetScore updates the scores map in a way where one score value is uniqe
+// UpdateScore updates the scores map in a way where one score value is uniqe
 // among the values.
 func (v *Votes) UpdateScore(entryID string, score int) {
 	for id, existingScore := range v.Scores {
@@ -186,6 +394,32 @@ func (v *Votes) UpdateScore(entryID string, score int) {
 	v.UpdatedAt = time.Now()
 }
 
+// SetScore sets the score of an entry without affecting other entries.
+func (v *Votes) SetScore(entryID string, score int) {
+	v.Scores[entryID] = score
+	v.UpdatedAt = time.Now()
+}
+
+// RemoveScore removes an entry from the ballot.
+func (v *Votes) RemoveScore(entryID string) {
+	delete(v.Scores, entryID)
+	v.UpdatedAt = time.Now()
+}
+
+// Cast reports whether the ballot gives a score to any entry, a record which
+// only holds comments is not a ballot and is not counted as a voter.
+func (v *Votes) Cast() bool {
+	if v == nil {
+		return false
+	}
+	for _, score := range v.Scores {
+		if score != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (db *DB) GetBattle(battleName string) (*Battle, error) {
 	var battle *Battle
 	err := db.BoltDB.View(func(tx *bolt.Tx) error {
@@ -204,67 +438,78 @@ func (db *DB) GetBattle(battleName string) (*Battle, error) {
 	return battle, nil
 }
 
-func (db *DB) OpenBattle(battleName string) error {
-	err := db.BoltDB.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(battlesBucketName))
-		if bucket == nil {
-			return NotFound
-		}
-		battle, err := getBattle(bucket, battleName)
-		if err != nil {
-			return err
-		}
-		battle.ClosedAt = time.Time{}
-		return putBattle(bucket, *battle)
-
+// SetPhase moves a battle to a new phase, InvalidTransition is returned if
+// the battle cannot move from its current phase to the new phase.
+func (db *DB) SetPhase(battleName string, phase Phase, actor string) error {
+	var from Phase
+	err := db.updateBattle(battleName, func(battle *Battle) error {
+		from = battle.Phase
+		return battle.SetPhase(phase, actor, time.Now())
 	})
 	if err != nil {
 		return err
 	}
+	db.publishTransition(battleName, from, phase)
 	return nil
 }
 
-func (db *DB) HideBattle(battleName string) error {
-	err := db.BoltDB.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(battlesBucketName))
-		if bucket == nil {
-			return NotFound
-		}
-		battle, err := getBattle(bucket, battleName)
-		if err != nil {
-			return err
-		}
-		battle.Hidden = true
-		return putBattle(bucket, *battle)
+// OpenBattle opens the battle for voting.
+func (db *DB) OpenBattle(battleName string, actor string) error {
+	return db.SetPhase(battleName, PhaseVoting, actor)
+}
 
+// CloseBattle closes voting and publishes the results.
+func (db *DB) CloseBattle(battleName string, actor string) error {
+	return db.SetPhase(battleName, PhaseResults, actor)
+}
+
+func (db *DB) HideBattle(battleName string, actor string) error {
+	return db.SetPhase(battleName, PhaseHidden, actor)
+}
+
+// UnhideBattle moves a hidden battle back to the phase it was hidden from.
+func (db *DB) UnhideBattle(battleName string, actor string) error {
+	var from, to Phase
+	err := db.updateBattle(battleName, func(battle *Battle) error {
+		from, to = battle.Phase, battle.Phase
+		if battle.Phase != PhaseHidden {
+			return nil
+		}
+		to = battle.previousVisiblePhase()
+		return battle.SetPhase(to, actor, time.Now())
 	})
 	if err != nil {
 		return err
 	}
+	// a battle which was not hidden is left as it was.
+	db.publishTransition(battleName, from, to)
 	return nil
 }
-func (db *DB) UnhideBattle(battleName string) error {
-	err := db.BoltDB.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(battlesBucketName))
-		if bucket == nil {
-			return NotFound
-		}
-		battle, err := getBattle(bucket, battleName)
-		if err != nil {
-			return err
-		}
-		battle.Hidden = false
-		return putBattle(bucket, *battle)
 
+// ScheduleBattle sets the scheduled opening and closing times of a battle,
+// zero times remove the schedule. A time set in the metadata file replaces
+// the one set here on the next scan.
+func (db *DB) ScheduleBattle(battleName string, opensAt, closesAt time.Time) error {
+	return db.updateBattle(battleName, func(battle *Battle) error {
+		battle.OpensAt = opensAt
+		battle.ClosesAt = closesAt
+		return battle.validateSchedule()
 	})
-	if err != nil {
-		return err
+}
+
+// validateSchedule returns InvalidSchedule if voting is scheduled to close
+// before it opens.
+func (d Battle) validateSchedule() error {
+	if !d.OpensAt.IsZero() && !d.ClosesAt.IsZero() && !d.ClosesAt.After(d.OpensAt) {
+		return fmt.Errorf("%w: closes_at %s is not after opens_at %s", InvalidSchedule,
+			d.ClosesAt.Format(time.RFC3339), d.OpensAt.Format(time.RFC3339))
 	}
 	return nil
 }
 
-func (db *DB) CloseBattle(battleName string) error {
-	err := db.BoltDB.Update(func(tx *bolt.Tx) error {
+// updateBattle loads a battle, applies fn to it and stores it.
+func (db *DB) updateBattle(battleName string, fn func(battle *Battle) error) error {
+	return db.BoltDB.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(battlesBucketName))
 		if bucket == nil {
 			return NotFound
@@ -273,14 +518,14 @@ func (db *DB) CloseBattle(battleName string) error {
 		if err != nil {
 			return err
 		}
-		battle.ClosedAt = time.Now()
+		if battle == nil {
+			return NotFound
+		}
+		if err := fn(battle); err != nil {
+			return err
+		}
 		return putBattle(bucket, *battle)
-
 	})
-	if err != nil {
-		return err
-	}
-	return nil
 }
 
 func (db *DB) GetAllBattles() ([]Battle, error) {
@@ -309,7 +554,32 @@ func (db *DB) GetAllBattles() ([]Battle, error) {
 	return battles, nil
 }
 
-func (db *DB) UpdateBattle(fsBattle scanner.Battle) error {
+// ScanReport describes how the entries found by a scan were matched to the
+// stored entries of a battle.
+type ScanReport struct {
+	Battle string `json:"battle"`
+	// Kept are entries matched by filename.
+	Kept []string `json:"kept"`
+	// Renamed are entries matched by content hash, they keep their ID and
+	// votes.
+	Renamed []Rename `json:"renamed"`
+	// Added are new entries.
+	Added []string `json:"added"`
+	// Removed are stored entries which were not found by the scan.
+	Removed []string `json:"removed"`
+	// Error is set if the battle could not be read or updated, the stored
+	// battle is left as it was.
+	Error string `json:"error,omitempty"`
+}
+
+type Rename struct {
+	ID   string `json:"id"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func (db *DB) UpdateBattle(fsBattle scanner.Battle) (ScanReport, error) {
+	report := ScanReport{Battle: fsBattle.Name}
 	err := db.BoltDB.Update(func(tx *bolt.Tx) error {
 		bucket, err := tx.CreateBucketIfNotExists([]byte(battlesBucketName))
 		if err != nil {
@@ -317,42 +587,127 @@ func (db *DB) UpdateBattle(fsBattle scanner.Battle) error {
 		}
 
 		newBattle := Battle{
-			Name:      fsBattle.Name,
+			Name:        fsBattle.Name,
+			Title:       fsBattle.Meta.Title,
+			Description: fsBattle.Meta.Description,
+			Theme:       fsBattle.Meta.Theme,
+			Scoring: ScoringConfig{
+				System: fsBattle.Meta.Scoring.System,
+				Points: fsBattle.Meta.Scoring.Points,
+			},
+			OpensAt:    fsBattle.Meta.OpensAt,
+			ClosesAt:   fsBattle.Meta.ClosesAt,
+			Comments:   CommentVisibility(fsBattle.Meta.Comments),
+			InviteOnly: fsBattle.Meta.InviteOnly,
+			Participation: ParticipationConfig{
+				Rule:    ParticipationRule(fsBattle.Meta.Participation.Rule),
+				Penalty: fsBattle.Meta.Participation.Penalty,
+			},
+			Roles:     RoleWeights(fsBattle.Meta.Roles),
 			CreatedAt: time.Now(),
-			Hidden:    true,
 		}
 
 		var oldBattle Battle
 		data := bucket.Get([]byte(fsBattle.Name))
-		if data != nil {
+		if data == nil {
+			if err := newBattle.SetPhase(PhaseHidden, ActorScan, newBattle.CreatedAt); err != nil {
+				return err
+			}
+		} else {
 			if err := yaml.Unmarshal(data, &oldBattle); err != nil {
 				return err
 			}
-			newBattle.Hidden = oldBattle.Hidden
 			newBattle.CreatedAt = oldBattle.CreatedAt
-			newBattle.ClosedAt = oldBattle.ClosedAt
+			newBattle.Phase = oldBattle.Phase
+			newBattle.Transitions = oldBattle.Transitions
+			newBattle.Reveal = oldBattle.Reveal
+			newBattle.VoterRoles = oldBattle.VoterRoles
+			// Schedules set through the api are kept unless the metadata
+			// file sets them.
+			if newBattle.OpensAt.IsZero() {
+				newBattle.OpensAt = oldBattle.OpensAt
+			}
+			if newBattle.ClosesAt.IsZero() {
+				newBattle.ClosesAt = oldBattle.ClosesAt
+			}
 		}
 
-		var newEntries []Entry
-		for _, fsEntry := range fsBattle.Entries {
-			newEntry := Entry{
-				ID:        xid.New().String(),
-				Author:    fsEntry.Author,
-				Title:     fsEntry.Title,
-				Filename:  fsEntry.Filename,
-				CreatedAt: time.Now(),
+		newEntries := make([]Entry, len(fsBattle.Entries))
+		matched := make(map[string]bool)
+		for i, fsEntry := range fsBattle.Entries {
+			newEntries[i] = Entry{
+				Author:      fsEntry.Author,
+				Title:       fsEntry.Title,
+				Filename:    fsEntry.Filename,
+				Hash:        fsEntry.Hash,
+				SubmittedAt: fsEntry.ModTime,
+			}
+			prevEntry, ok := oldBattle.GetEntryByFilename(fsEntry.Filename)
+			if ok {
+				newEntries[i].inherit(prevEntry, fsBattle.Meta.Entries)
+				matched[prevEntry.ID] = true
+				report.Kept = append(report.Kept, fsEntry.Filename)
 			}
+		}
 
-			prevEntry, ok := oldBattle.GetEntryByFilename(newEntry.Filename)
+		// Entries not found by filename are matched by content hash to the
+		// remaining stored entries so that renamed files keep their votes.
+		for i, newEntry := range newEntries {
+			if newEntry.ID != "" {
+				continue
+			}
+			prevEntry, ok := oldBattle.getUnmatchedEntryByHash(newEntry.Hash, matched)
 			if ok {
-				newEntry.ID = prevEntry.ID
-				newEntry.CreatedAt = prevEntry.CreatedAt
+				newEntries[i].inherit(prevEntry, fsBattle.Meta.Entries)
+				matched[prevEntry.ID] = true
+				report.Renamed = append(report.Renamed, Rename{
+					ID:   prevEntry.ID,
+					From: prevEntry.Filename,
+					To:   newEntry.Filename,
+				})
+				continue
+			}
+			newEntries[i].ID = xid.New().String()
+			newEntries[i].CreatedAt = time.Now()
+			report.Added = append(report.Added, newEntry.Filename)
+		}
+
+		for _, e := range oldBattle.Entries {
+			if !matched[e.ID] {
+				report.Removed = append(report.Removed, e.Filename)
 			}
-			newEntries = append(newEntries, newEntry)
 		}
 
 		newBattle.Entries = newEntries
 
+		if _, err := NewScoringSystem(newBattle.Scoring); err != nil {
+			return fmt.Errorf("battle %s: %w", newBattle.Name, err)
+		}
+		if !newBattle.Comments.Valid() {
+			return fmt.Errorf("battle %s: unknown comment visibility: %s", newBattle.Name, newBattle.Comments)
+		}
+		if err := newBattle.Participation.Validate(); err != nil {
+			return fmt.Errorf("battle %s: %w", newBattle.Name, err)
+		}
+		if err := newBattle.Roles.Validate(); err != nil {
+			return fmt.Errorf("battle %s: %w", newBattle.Name, err)
+		}
+		if err := newBattle.validateSchedule(); err != nil {
+			return fmt.Errorf("battle %s: %w", newBattle.Name, err)
+		}
+		// combined scores are shares of each role's points, points
+		// subtracted from a role would not mean the same in every role.
+		if newBattle.Weighted() && newBattle.Participation.Rule == ParticipationPenalty {
+			return fmt.Errorf("battle %s: %w: penalty cannot be used with roles", newBattle.Name, InvalidParticipationRule)
+		}
+		for _, name := range fsBattle.Meta.TieBreak {
+			rule := TieBreakRule(name)
+			if !rule.Valid() {
+				return fmt.Errorf("battle %s: unknown tie-break rule: %s", newBattle.Name, name)
+			}
+			newBattle.TieBreak = append(newBattle.TieBreak, rule)
+		}
+
 		slog.Info("storing", "battle", newBattle)
 		if err := putBattle(bucket, newBattle); err != nil {
 			return err
@@ -360,7 +715,7 @@ func (db *DB) UpdateBattle(fsBattle scanner.Battle) error {
 
 		return nil
 	})
-	return err
+	return report, err
 }
 
 func (db *DB) GetVotes(battleName string, voterID string) (*Votes, error) {
@@ -381,7 +736,29 @@ func (db *DB) GetVotes(battleName string, voterID string) (*Votes, error) {
 	return votes, nil
 }
 
+// GetAllVotes returns the ballots of a battle which are counted, excluded
+// ballots and records only holding comments are left out.
 func (db *DB) GetAllVotes(battleName string) ([]Votes, error) {
+	votes, err := db.GetAllBallots(battleName)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(votes, func(v Votes) bool { return v.Excluded || !v.Cast() }), nil
+}
+
+// GetIncludedBallots returns the ballots of a battle which are not excluded
+// including records only holding comments.
+func (db *DB) GetIncludedBallots(battleName string) ([]Votes, error) {
+	votes, err := db.GetAllBallots(battleName)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(votes, func(v Votes) bool { return v.Excluded }), nil
+}
+
+// GetAllBallots returns all ballots of a battle including excluded ones and
+// records only holding comments.
+func (db *DB) GetAllBallots(battleName string) ([]Votes, error) {
 	var votes []Votes
 
 	err := db.BoltDB.View(func(tx *bolt.Tx) error {
@@ -407,11 +784,15 @@ func (db *DB) GetAllVotes(battleName string) ([]Votes, error) {
 	return votes, nil
 }
 
-func (db *DB) UpdateVote(battleName string, entryID string, voterID string, score int) error {
-	if score < 1 || score > 3 {
-		return InvalidScore
-	}
-
+// UpdateVote sets the score of an entry on the ballot of a voter, a score of 0
+// removes the entry from the ballot. Valid scores depend on the scoring system
+// of the battle. The metadata of the request is recorded on the ballot.
+func (db *DB) UpdateVote(battleName string, entryID string, voterID string, score int, req BallotRequest) error {
+	// changed is set if the vote cast or cleared a ballot.
+	var (
+		changed bool
+		voters  int
+	)
 	err := db.BoltDB.Update(func(tx *bolt.Tx) error {
 
 		battlesBucket := tx.Bucket([]byte(battlesBucketName))
@@ -425,10 +806,22 @@ func (db *DB) UpdateVote(battleName string, entryID string, voterID string, scor
 			return err
 		}
 
-		_, ok := battle.GetEntryByID(entryID)
+		entry, ok := battle.GetEntryByID(entryID)
 		if !ok {
 			return NotFound
 		}
+		// removing a score is allowed so that ballots from before the
+		// entrant was known can be fixed.
+		if score != 0 && entry.IsEntrant(voterID) {
+			return SelfVote
+		}
+
+		system := battle.ScoringSystem()
+		if score != 0 {
+			if err := system.Validate(score, len(battle.Entries)); err != nil {
+				return err
+			}
+		}
 
 		votesBucket, err := tx.CreateBucketIfNotExists(newVotesBucketKey(battleName))
 		if err != nil {
@@ -441,6 +834,7 @@ func (db *DB) UpdateVote(battleName string, entryID string, voterID string, scor
 			return err
 		}
 
+		wasCast := votes.Cast()
 		if votes == nil {
 			votes = &Votes{
 				VoterID:   voterID,
@@ -451,21 +845,40 @@ func (db *DB) UpdateVote(battleName string, entryID string, voterID string, scor
 		if votes.Scores == nil {
 			votes.Scores = make(map[string]int)
 		}
-		votes.UpdateScore(entryID, score)
+		switch {
+		case score == 0:
+			votes.RemoveScore(entryID)
+		case system.Unique():
+			votes.UpdateScore(entryID, score)
+		default:
+			votes.SetScore(entryID, score)
+		}
+		votes.Meta.record(req, entryID, score)
 
 		if err := putVotes(votesBucket, *votes); err != nil {
 			return err
 		}
-
-		return nil
+		if changed = votes.Cast() != wasCast; changed {
+			voters, err = countBallots(votesBucket)
+		}
+		return err
 	})
-
-	return err
-
+	if err != nil {
+		return err
+	}
+	if changed {
+		db.publishVoterCount(battleName, voters)
+	}
+	return nil
 }
 
+// RemoveVotes clears the ballot of a voter. A ballot with comments is kept
+// without scores.
 func (db *DB) RemoveVotes(battleName string, voterID string) error {
-
+	var (
+		removed bool
+		voters  int
+	)
 	err := db.BoltDB.Update(func(tx *bolt.Tx) error {
 
 		battlesBucket := tx.Bucket([]byte(battlesBucketName))
@@ -484,15 +897,33 @@ func (db *DB) RemoveVotes(battleName string, voterID string) error {
 			return err
 		}
 
-		if err := votesBucket.Delete([]byte(voterID)); err != nil {
+		votes, err := getVotes(votesBucket, battleName, voterID)
+		if err != nil {
 			return err
 		}
-
-		return nil
+		removed = votes.Cast()
+		// comments are kept, only the scores are cleared. Excluded ballots
+		// are kept so that clearing them does not lift the exclusion.
+		if votes != nil && (len(votes.Comments) > 0 || votes.Excluded) {
+			votes.Scores = make(ScoreMap)
+			votes.UpdatedAt = time.Now()
+			err = putVotes(votesBucket, *votes)
+		} else {
+			err = votesBucket.Delete([]byte(voterID))
+		}
+		if err != nil {
+			return err
+		}
+		voters, err = countBallots(votesBucket)
+		return err
 	})
-
-	return err
-
+	if err != nil {
+		return err
+	}
+	if removed {
+		db.publishVoterCount(battleName, voters)
+	}
+	return nil
 }
 
 func getBattle(bucket *bolt.Bucket, battleName string) (*Battle, error) {
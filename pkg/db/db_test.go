@@ -0,0 +1,207 @@
+package db
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/some-programs/battlr/pkg/events"
+	"github.com/some-programs/battlr/pkg/scanner"
+	bolt "go.etcd.io/bbolt"
+)
+
+// newTestDB returns an empty database in a temporary directory.
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	boltdb, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { boltdb.Close() })
+	return &DB{BoltDB: boltdb, Events: events.NewBus()}
+}
+
+// newTestBattle stores a battle with one entry per filename and returns it.
+func newTestBattle(t *testing.T, db *DB, name string, filenames ...string) Battle {
+	t.Helper()
+	fsBattle := scanner.Battle{Name: name}
+	for _, f := range filenames {
+		fsBattle.Entries = append(fsBattle.Entries, scanner.Entry{
+			Filename: f,
+			Title:    f,
+			Path:     name + "/" + f,
+			Hash:     f,
+		})
+	}
+	if _, err := db.UpdateBattle(fsBattle); err != nil {
+		t.Fatal(err)
+	}
+	battle, err := db.GetBattle(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *battle
+}
+
+func TestCommentsDoNotCountAsVoters(t *testing.T) {
+	db := newTestDB(t)
+	battle := newTestBattle(t, db, "b", "a.wav", "b.wav", "c.wav")
+	entry := battle.Entries[0].ID
+	evs, unsubscribe := db.Events.Subscribe("b")
+	defer unsubscribe()
+
+	countVoters := func() int {
+		t.Helper()
+		n, err := db.CountVoters("b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		votes, err := db.GetAllVotes("b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(votes) != n {
+			t.Errorf("%d votes, %d voters", len(votes), n)
+		}
+		return n
+	}
+	published := func() []int {
+		var res []int
+		for {
+			select {
+			case ev := <-evs:
+				res = append(res, ev.Voters)
+			default:
+				return res
+			}
+		}
+	}
+
+	if err := db.UpdateComment("b", entry, "commenter", "nice"); err != nil {
+		t.Fatal(err)
+	}
+	if n := countVoters(); n != 0 {
+		t.Errorf("after comment: %d voters, want 0", n)
+	}
+	if err := db.UpdateVote("b", entry, "commenter", 3, BallotRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := countVoters(); n != 1 {
+		t.Errorf("after vote: %d voters, want 1", n)
+	}
+	if err := db.UpdateVote("b", battle.Entries[1].ID, "commenter", 2, BallotRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	// the comment keeps the record when the scores are removed.
+	if err := db.RemoveVotes("b", "commenter"); err != nil {
+		t.Fatal(err)
+	}
+	if n := countVoters(); n != 0 {
+		t.Errorf("after removing votes: %d voters, want 0", n)
+	}
+	ballots, err := db.GetAllBallots("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ballots) != 1 || len(ballots[0].Comments) != 1 {
+		t.Errorf("ballots = %+v, want the comment kept", ballots)
+	}
+	if got := published(); len(got) != 2 || got[0] != 1 || got[1] != 0 {
+		t.Errorf("published voter counts %v, want [1 0]", got)
+	}
+}
+
+func TestUpdateBattleRejectsPenaltyWithRoles(t *testing.T) {
+	db := newTestDB(t)
+	fsBattle := scanner.Battle{
+		Name: "b",
+		Meta: scanner.Meta{
+			Participation: scanner.ParticipationMeta{Rule: "penalty", Penalty: 3},
+			Roles:         map[string]int{"judge": 1, RolePublic: 1},
+		},
+	}
+	if _, err := db.UpdateBattle(fsBattle); !errors.Is(err, InvalidParticipationRule) {
+		t.Errorf("err = %v, want %v", err, InvalidParticipationRule)
+	}
+	fsBattle.Meta.Participation.Rule = "disqualify"
+	if _, err := db.UpdateBattle(fsBattle); err != nil {
+		t.Errorf("disqualify with roles: %v", err)
+	}
+}
+
+func TestScheduleBattle(t *testing.T) {
+	db := newTestDB(t)
+	newTestBattle(t, db, "b", "a.wav")
+	opens := time.Date(2024, 5, 25, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		opensAt  time.Time
+		closesAt time.Time
+		err      error
+	}{
+		{"schedule", opens, opens.Add(time.Hour), nil},
+		{"only opening", opens, time.Time{}, nil},
+		{"only closing", time.Time{}, opens, nil},
+		{"closes before opening", opens, opens.Add(-time.Hour), InvalidSchedule},
+		{"closes when opening", opens, opens, InvalidSchedule},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := db.ScheduleBattle("b", tt.opensAt, tt.closesAt); !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestScheduleMetadataWins(t *testing.T) {
+	db := newTestDB(t)
+	newTestBattle(t, db, "b", "a.wav")
+	opens := time.Date(2024, 5, 25, 20, 0, 0, 0, time.UTC)
+	if err := db.ScheduleBattle("b", opens, opens.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	closes := opens.Add(2 * time.Hour)
+	if _, err := db.UpdateBattle(scanner.Battle{Name: "b", Meta: scanner.Meta{ClosesAt: closes}}); err != nil {
+		t.Fatal(err)
+	}
+	battle, err := db.GetBattle("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !battle.OpensAt.Equal(opens) || !battle.ClosesAt.Equal(closes) {
+		t.Errorf("schedule %v - %v, want %v - %v", battle.OpensAt, battle.ClosesAt, opens, closes)
+	}
+}
+
+func TestGetIncludedBallotsKeepsComments(t *testing.T) {
+	db := newTestDB(t)
+	battle := newTestBattle(t, db, "b", "a.wav", "b.wav")
+	entry := battle.Entries[0].ID
+
+	if err := db.UpdateComment("b", entry, "commenter", "nice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateComment("b", entry, "excluded", "spam"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateVote("b", entry, "excluded", 3, BallotRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetBallotExcluded("b", "excluded", true); err != nil {
+		t.Fatal(err)
+	}
+
+	ballots, err := db.GetIncludedBallots("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ballots) != 1 || ballots[0].VoterID != "commenter" {
+		t.Fatalf("ballots = %+v, want only the comment-only ballot", ballots)
+	}
+	comments := VisibleComments(ballots)
+	if len(comments[entry]) != 1 || comments[entry][0].Text != "nice" {
+		t.Errorf("comments = %+v, want the comment of the comment-only ballot", comments)
+	}
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	bolt "go.etcd.io/bbolt"
+)
+
+// SetEntrant links the entry to the voter id of its entrant, an empty voterID
+// removes the link. A score the entrant already gave to the entry is removed
+// from their ballot.
+func (db *DB) SetEntrant(battleName string, entryID string, voterID string) error {
+	return db.BoltDB.Update(func(tx *bolt.Tx) error {
+		battlesBucket := tx.Bucket([]byte(battlesBucketName))
+		if battlesBucket == nil {
+			return NotFound
+		}
+		battle, err := getBattle(battlesBucket, battleName)
+		if err != nil {
+			return err
+		}
+		if battle == nil {
+			return NotFound
+		}
+		idx := -1
+		for i, e := range battle.Entries {
+			if e.ID == entryID {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			return NotFound
+		}
+		battle.Entries[idx].EntrantID = voterID
+		if err := putBattle(battlesBucket, *battle); err != nil {
+			return err
+		}
+		if voterID == "" {
+			return nil
+		}
+
+		votesBucket := tx.Bucket(newVotesBucketKey(battleName))
+		if votesBucket == nil {
+			return nil
+		}
+		votes, err := getVotes(votesBucket, battleName, voterID)
+		if err != nil || votes == nil {
+			return err
+		}
+		if _, ok := votes.Scores[entryID]; !ok {
+			return nil
+		}
+		votes.RemoveScore(entryID)
+		return putVotes(votesBucket, *votes)
+	})
+}
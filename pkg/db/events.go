@@ -0,0 +1,59 @@
+package db
+
+import (
+	"github.com/some-programs/battlr/pkg/events"
+	bolt "go.etcd.io/bbolt"
+	"gopkg.in/yaml.v3"
+)
+
+// CountVoters returns the number of voters with a ballot in a battle.
+func (db *DB) CountVoters(battleName string) (int, error) {
+	var n int
+	err := db.BoltDB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(newVotesBucketKey(battleName))
+		if bucket == nil {
+			return nil
+		}
+		var err error
+		n, err = countBallots(bucket)
+		return err
+	})
+	return n, err
+}
+
+// countBallots counts the cast ballots in a votes bucket, excluded ballots
+// are counted so that voters are not told about the exclusion.
+func countBallots(bucket *bolt.Bucket) (int, error) {
+	var n int
+	err := bucket.ForEach(func(k, v []byte) error {
+		var votes Votes
+		if err := yaml.Unmarshal(v, &votes); err != nil {
+			return err
+		}
+		if votes.Cast() {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// publishTransition publishes the events caused by a phase change.
+func (db *DB) publishTransition(battleName string, from, to Phase) {
+	if from == to {
+		return
+	}
+	if to == PhaseVoting {
+		db.Events.Publish(events.Event{Type: events.BattleOpened, Battle: battleName})
+	}
+	if from == PhaseVoting {
+		db.Events.Publish(events.Event{Type: events.BattleClosed, Battle: battleName})
+	}
+	if to == PhaseResults {
+		db.Events.Publish(events.Event{Type: events.ResultsPublished, Battle: battleName})
+	}
+}
+
+func (db *DB) publishVoterCount(battleName string, voters int) {
+	db.Events.Publish(events.Event{Type: events.VoterCount, Battle: battleName, Voters: voters})
+}
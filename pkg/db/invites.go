@@ -0,0 +1,278 @@
+package db
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/rs/xid"
+	bolt "go.etcd.io/bbolt"
+)
+
+const invitesBucketNamePrefix = "invites⊳"
+
+var (
+	InvalidInvite   = errors.New("invalid invite token")
+	DuplicateInvite = errors.New("another invite was already redeemed")
+)
+
+// Invite is a token which allows voting in an invite only battle. A
+// single-use token can be redeemed by one client, a personal token can be
+// redeemed by every client of the person it was issued to. Everyone who
+// redeemed the token votes as the same voter.
+type Invite struct {
+	ID    string `yaml:"id" json:"id"`
+	Token string `yaml:"token" json:"token"`
+	// Name is the person a personal token was issued to, single-use tokens
+	// have no name.
+	Name      string    `yaml:"name" json:"name,omitempty"`
+	CreatedAt time.Time `yaml:"created_at" json:"created_at"`
+	// Clients are the voter ids of the clients which redeemed the token.
+	Clients []string `yaml:"clients" json:"clients"`
+}
+
+// Personal reports whether the token can be redeemed by several clients.
+func (i Invite) Personal() bool {
+	return i.Name != ""
+}
+
+// VoterID is the voter id of the token holder.
+func (i Invite) VoterID() string {
+	return "invite:" + i.ID
+}
+
+// InviteStats counts the invites of a battle.
+type InviteStats struct {
+	Issued int
+	// Used is the number of tokens which have a ballot.
+	Used int
+}
+
+func newInvitesBucketKey(battleName string) []byte {
+	key := []byte(invitesBucketNamePrefix)
+	key = append(key, []byte(battleName)...)
+	return key
+}
+
+func newInviteToken() (string, error) {
+	b := make([]byte, 15)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CreateInvites issues count single-use tokens and a personal token for each
+// name.
+func (db *DB) CreateInvites(battleName string, count int, names []string) ([]Invite, error) {
+	var invites []Invite
+	now := time.Now()
+	for i := 0; i < count+len(names); i++ {
+		token, err := newInviteToken()
+		if err != nil {
+			return nil, err
+		}
+		invite := Invite{
+			ID:        xid.New().String(),
+			Token:     token,
+			CreatedAt: now,
+		}
+		if i >= count {
+			invite.Name = names[i-count]
+		}
+		invites = append(invites, invite)
+	}
+	err := db.BoltDB.Update(func(tx *bolt.Tx) error {
+		battlesBucket := tx.Bucket([]byte(battlesBucketName))
+		if battlesBucket == nil {
+			return NotFound
+		}
+		battle, err := getBattle(battlesBucket, battleName)
+		if err != nil {
+			return err
+		}
+		if battle == nil {
+			return NotFound
+		}
+		bucket, err := tx.CreateBucketIfNotExists(newInvitesBucketKey(battleName))
+		if err != nil {
+			return err
+		}
+		for _, invite := range invites {
+			if err := storeYaml(bucket, []byte(invite.Token), invite); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// GetInvites returns the invites of a battle ordered by creation.
+func (db *DB) GetInvites(battleName string) ([]Invite, error) {
+	var invites []Invite
+	err := db.BoltDB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(newInvitesBucketKey(battleName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			invite, err := retreiveYaml[Invite](bucket, k)
+			if err != nil {
+				return err
+			}
+			invites = append(invites, *invite)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(invites, func(a, b Invite) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return invites, nil
+}
+
+// DeleteInvite revokes an invite, ballots already cast with it are kept and
+// can be left out of the tally with SetBallotExcluded.
+func (db *DB) DeleteInvite(battleName string, id string) error {
+	return db.BoltDB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(newInvitesBucketKey(battleName))
+		if bucket == nil {
+			return NotFound
+		}
+		var token []byte
+		if err := bucket.ForEach(func(k, v []byte) error {
+			invite, err := retreiveYaml[Invite](bucket, k)
+			if err != nil {
+				return err
+			}
+			if invite.ID == id {
+				token = k
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		if token == nil {
+			return NotFound
+		}
+		return bucket.Delete(token)
+	})
+}
+
+// RedeemInvite lets clientID vote with the token. Redeeming a token again
+// from the same client does nothing, InvalidInvite is returned for unknown
+// tokens and for single-use tokens redeemed by another client.
+// DuplicateInvite is returned if the client already redeemed another token of
+// the battle, so that a client cannot use up several tokens. Entries the
+// client uploaded before redeeming are moved to the voter id of the invite.
+func (db *DB) RedeemInvite(battleName string, token string, clientID string) (*Invite, error) {
+	var invite *Invite
+	err := db.BoltDB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(newInvitesBucketKey(battleName))
+		if bucket == nil || token == "" {
+			return InvalidInvite
+		}
+		var err error
+		invite, err = retreiveYaml[Invite](bucket, []byte(token))
+		if err != nil {
+			return err
+		}
+		if invite == nil {
+			return InvalidInvite
+		}
+		if slices.Contains(invite.Clients, clientID) {
+			return nil
+		}
+		if !invite.Personal() && len(invite.Clients) > 0 {
+			return InvalidInvite
+		}
+		if err := bucket.ForEach(func(k, v []byte) error {
+			other, err := retreiveYaml[Invite](bucket, k)
+			if err != nil {
+				return err
+			}
+			if slices.Contains(other.Clients, clientID) {
+				return DuplicateInvite
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		invite.Clients = append(invite.Clients, clientID)
+		if err := storeYaml(bucket, []byte(token), *invite); err != nil {
+			return err
+		}
+
+		battlesBucket := tx.Bucket([]byte(battlesBucketName))
+		if battlesBucket == nil {
+			return nil
+		}
+		battle, err := getBattle(battlesBucket, battleName)
+		if err != nil || battle == nil {
+			return err
+		}
+		changed := false
+		for i, e := range battle.Entries {
+			if e.UploaderID == clientID {
+				battle.Entries[i].UploaderID = invite.VoterID()
+				changed = true
+			}
+		}
+		if !changed {
+			return nil
+		}
+		return putBattle(battlesBucket, *battle)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// GetInviteByClient returns the invite redeemed by clientID, nil if the
+// client has not redeemed one.
+func (db *DB) GetInviteByClient(battleName string, clientID string) (*Invite, error) {
+	invites, err := db.GetInvites(battleName)
+	if err != nil {
+		return nil, err
+	}
+	for _, invite := range invites {
+		if slices.Contains(invite.Clients, clientID) {
+			return &invite, nil
+		}
+	}
+	return nil, nil
+}
+
+// InviteStats counts the issued invites and the invites used to vote.
+func (db *DB) InviteStats(battleName string) (InviteStats, error) {
+	var stats InviteStats
+	invites, err := db.GetInvites(battleName)
+	if err != nil {
+		return stats, err
+	}
+	votes, err := db.GetAllVotes(battleName)
+	if err != nil {
+		return stats, err
+	}
+	voters := make(map[string]bool, len(votes))
+	for _, v := range votes {
+		voters[v.VoterID] = true
+	}
+	stats.Issued = len(invites)
+	for _, invite := range invites {
+		if voters[invite.VoterID()] {
+			stats.Used++
+		}
+	}
+	return stats, nil
+}
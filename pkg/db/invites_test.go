@@ -0,0 +1,124 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/some-programs/battlr/pkg/scanner"
+)
+
+func TestRedeemInviteMovesUploads(t *testing.T) {
+	db := newTestDB(t)
+	newTestBattle(t, db, "b", "a.wav")
+	if err := db.SetPhase("b", PhaseSubmission, "test"); err != nil {
+		t.Fatal(err)
+	}
+	entry := Entry{Author: "Al", Title: "Song", Filename: "Al - Song.wav", UploaderID: "cookie:al"}
+	entry, _, err := db.SubmitEntry("b", entry, func(Entry) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	invites, err := db.CreateInvites("b", 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invite, err := db.RedeemInvite("b", invites[0].Token, "cookie:al")
+	if err != nil {
+		t.Fatal(err)
+	}
+	battle, err := db.GetBattle("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := battle.GetEntryByID(entry.ID)
+	if !ok {
+		t.Fatalf("entry %s not found", entry.ID)
+	}
+	if got.UploaderID != invite.VoterID() {
+		t.Errorf("uploader = %q, want %q", got.UploaderID, invite.VoterID())
+	}
+}
+
+func TestParticipationInviteOnlyEntrant(t *testing.T) {
+	db := newTestDB(t)
+	fsBattle := scanner.Battle{
+		Name: "b",
+		Entries: []scanner.Entry{
+			{Filename: "a.wav", Title: "a", Path: "b/a.wav", Hash: "a"},
+			{Filename: "b.wav", Title: "b", Path: "b/b.wav", Hash: "b"},
+		},
+		Meta: scanner.Meta{
+			InviteOnly:    true,
+			Participation: scanner.ParticipationMeta{Rule: "disqualify"},
+		},
+	}
+	if _, err := db.UpdateBattle(fsBattle); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetPhase("b", PhaseSubmission, "test"); err != nil {
+		t.Fatal(err)
+	}
+	// the entry is uploaded before the invite is redeemed.
+	entry := Entry{Author: "Al", Title: "Song", Filename: "Al - Song.wav", UploaderID: "cookie:al"}
+	entry, _, err := db.SubmitEntry("b", entry, func(Entry) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	invites, err := db.CreateInvites("b", 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invite, err := db.RedeemInvite("b", invites[0].Token, "cookie:al")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetPhase("b", PhaseVoting, "test"); err != nil {
+		t.Fatal(err)
+	}
+	battle, err := db.GetBattle("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range battle.Entries {
+		if e.ID == entry.ID {
+			continue
+		}
+		if err := db.UpdateVote("b", e.ID, invite.VoterID(), 3-i, BallotRequest{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	votes, err := db.GetAllVotes("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := battle.Results(votes)
+	if len(results.Disqualified) != 0 {
+		t.Errorf("disqualified %+v, the entrant voted with their invite", results.Disqualified)
+	}
+}
+
+func TestRedeemInviteOncePerClient(t *testing.T) {
+	db := newTestDB(t)
+	newTestBattle(t, db, "b", "a.wav")
+	invites, err := db.CreateInvites("b", 2, []string{"Al"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.RedeemInvite("b", invites[0].Token, "cookie:al"); err != nil {
+		t.Fatal(err)
+	}
+	// redeeming the same token again does nothing.
+	if _, err := db.RedeemInvite("b", invites[0].Token, "cookie:al"); err != nil {
+		t.Errorf("redeeming again: %v", err)
+	}
+	for _, invite := range invites[1:] {
+		if _, err := db.RedeemInvite("b", invite.Token, "cookie:al"); !errors.Is(err, DuplicateInvite) {
+			t.Errorf("redeeming %s: err = %v, want %v", invite.ID, err, DuplicateInvite)
+		}
+	}
+	if _, err := db.RedeemInvite("b", invites[1].Token, "cookie:bo"); err != nil {
+		t.Errorf("other client: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const listensBucketNamePrefix = "listens⊳"
+
+// Listens records when a client first downloaded each entry of a battle.
+type Listens struct {
+	ClientID string `yaml:"client_id"`
+	// Entries maps entry ids to the time of the first download.
+	Entries map[string]time.Time `yaml:"entries"`
+}
+
+func newListensBucketKey(battleName string) []byte {
+	key := []byte(listensBucketNamePrefix)
+	key = append(key, []byte(battleName)...)
+	return key
+}
+
+// RecordListen stores the first download of an entry by the client, later
+// downloads of the same entry are ignored.
+func (db *DB) RecordListen(battleName string, entryID string, clientID string) error {
+	return db.RecordListens(battleName, []string{entryID}, clientID)
+}
+
+// RecordListens stores the first download of each of the entries by the
+// client, for example when all entries are downloaded at once.
+func (db *DB) RecordListens(battleName string, entryIDs []string, clientID string) error {
+	listens, err := db.GetListens(battleName, clientID)
+	if err != nil {
+		return err
+	}
+	// players request the file many times, most requests need no write.
+	heard := true
+	for _, id := range entryIDs {
+		if _, ok := listens.Entries[id]; !ok {
+			heard = false
+		}
+	}
+	if heard {
+		return nil
+	}
+	return db.BoltDB.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(newListensBucketKey(battleName))
+		if err != nil {
+			return err
+		}
+		listens, err := retreiveYaml[Listens](bucket, []byte(clientID))
+		if err != nil {
+			return err
+		}
+		if listens == nil {
+			listens = &Listens{ClientID: clientID, Entries: make(map[string]time.Time)}
+		}
+		now := time.Now()
+		for _, id := range entryIDs {
+			if _, ok := listens.Entries[id]; !ok {
+				listens.Entries[id] = now
+			}
+		}
+		return storeYaml(bucket, []byte(clientID), *listens)
+	})
+}
+
+// GetListens returns the downloads of the client, the entries are empty if
+// it has not downloaded any.
+func (db *DB) GetListens(battleName string, clientID string) (Listens, error) {
+	res := Listens{ClientID: clientID}
+	err := db.BoltDB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(newListensBucketKey(battleName))
+		if bucket == nil {
+			return nil
+		}
+		listens, err := retreiveYaml[Listens](bucket, []byte(clientID))
+		if err != nil || listens == nil {
+			return err
+		}
+		res = *listens
+		return nil
+	})
+	return res, err
+}
+
+// First returns the time of the first download, zero if there is none.
+func (l Listens) First() time.Time {
+	var first time.Time
+	for _, t := range l.Entries {
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+	}
+	return first
+}
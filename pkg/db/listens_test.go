@@ -0,0 +1,31 @@
+package db
+
+import "testing"
+
+func TestRecordListens(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.RecordListen("b", "a", "cookie:x"); err != nil {
+		t.Fatal(err)
+	}
+	first, err := db.GetListens("b", "cookie:x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordListens("b", []string{"a", "b", "c"}, "cookie:x"); err != nil {
+		t.Fatal(err)
+	}
+	listens, err := db.GetListens("b", "cookie:x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listens.Entries) != 3 {
+		t.Errorf("entries = %v, want a, b and c", listens.Entries)
+	}
+	// the first download of an entry is kept.
+	if !listens.Entries["a"].Equal(first.Entries["a"]) {
+		t.Errorf("first listen of a changed from %v to %v", first.Entries["a"], listens.Entries["a"])
+	}
+	if !listens.First().Equal(first.Entries["a"]) {
+		t.Errorf("first listen %v, want %v", listens.First(), first.Entries["a"])
+	}
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const notesBucketNamePrefix = "notes⊳"
+
+// MaxNoteLength is the maximum number of characters in a note.
+const MaxNoteLength = 10000
+
+var InvalidNote = errors.New("invalid note")
+
+// Notes are the private notes of a voter in a battle, they are never shown to
+// anyone else.
+type Notes struct {
+	VoterID   string    `yaml:"voter_id"`
+	UpdatedAt time.Time `yaml:"updated_at"`
+	// Notes are keyed by entry id.
+	Notes map[string]string `yaml:"notes"`
+}
+
+func newNotesBucketKey(battleName string) []byte {
+	key := []byte(notesBucketNamePrefix)
+	key = append(key, []byte(battleName)...)
+	return key
+}
+
+// GetNotes returns the notes of a voter, nil if there are none.
+func (db *DB) GetNotes(battleName string, voterID string) (*Notes, error) {
+	var notes *Notes
+	err := db.BoltDB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(newNotesBucketKey(battleName))
+		if bucket == nil {
+			return nil
+		}
+		var err error
+		notes, err = retreiveYaml[Notes](bucket, []byte(voterID))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// UpdateNote sets the note of a voter on an entry, an empty text removes the
+// note.
+func (db *DB) UpdateNote(battleName string, entryID string, voterID string, text string) error {
+	if utf8.RuneCountInString(text) > MaxNoteLength {
+		return fmt.Errorf("%w: longer than %d characters", InvalidNote, MaxNoteLength)
+	}
+	return db.BoltDB.Update(func(tx *bolt.Tx) error {
+		battlesBucket := tx.Bucket([]byte(battlesBucketName))
+		if battlesBucket == nil {
+			return NotFound
+		}
+		battle, err := getBattle(battlesBucket, battleName)
+		if err != nil {
+			return err
+		}
+		if battle == nil {
+			return NotFound
+		}
+		if _, ok := battle.GetEntryByID(entryID); !ok {
+			return NotFound
+		}
+
+		bucket, err := tx.CreateBucketIfNotExists(newNotesBucketKey(battleName))
+		if err != nil {
+			return err
+		}
+		notes, err := retreiveYaml[Notes](bucket, []byte(voterID))
+		if err != nil {
+			return err
+		}
+		if notes == nil {
+			notes = &Notes{VoterID: voterID}
+		}
+		if notes.Notes == nil {
+			notes.Notes = make(map[string]string)
+		}
+		if strings.TrimSpace(text) == "" {
+			delete(notes.Notes, entryID)
+		} else {
+			notes.Notes[entryID] = text
+		}
+		if len(notes.Notes) == 0 {
+			return bucket.Delete([]byte(voterID))
+		}
+		notes.UpdatedAt = time.Now()
+		return storeYaml(bucket, []byte(voterID), *notes)
+	})
+}
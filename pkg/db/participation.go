@@ -0,0 +1,98 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+)
+
+var InvalidParticipationRule = errors.New("invalid participation rule")
+
+// ParticipationRule selects what happens to the entries of entrants who did
+// not cast a full ballot.
+type ParticipationRule string
+
+const (
+	// ParticipationNone does not require entrants to vote, this is the
+	// default.
+	ParticipationNone ParticipationRule = ""
+	// ParticipationPenalty subtracts ParticipationConfig.Penalty from the
+	// score of the entry, weighted battles cannot use it.
+	ParticipationPenalty ParticipationRule = "penalty"
+	// ParticipationDisqualify leaves the entry out of the places.
+	ParticipationDisqualify ParticipationRule = "disqualify"
+)
+
+// ParticipationConfig requires entrants to vote. Only entries with a known
+// entrant, see Entry.IsEntrant, are checked.
+type ParticipationConfig struct {
+	Rule ParticipationRule `yaml:"rule"`
+	// Penalty is the number of points subtracted with ParticipationPenalty.
+	Penalty int `yaml:"penalty"`
+}
+
+// Enabled reports whether entrants are required to vote.
+func (c ParticipationConfig) Enabled() bool {
+	return c.Rule != ParticipationNone
+}
+
+func (c ParticipationConfig) Validate() error {
+	switch c.Rule {
+	case ParticipationNone, ParticipationDisqualify:
+	case ParticipationPenalty:
+		if c.Penalty <= 0 {
+			return fmt.Errorf("%w: penalty must be positive", InvalidParticipationRule)
+		}
+	default:
+		return fmt.Errorf("%w: %s", InvalidParticipationRule, c.Rule)
+	}
+	return nil
+}
+
+// FullBallotSize returns the number of entries the voter has to vote for to
+// cast a full ballot, the entries of the voter are not counted.
+func (d Battle) FullBallotSize(voterID string) int {
+	eligible := 0
+	for _, e := range d.Entries {
+		if !e.IsEntrant(voterID) {
+			eligible++
+		}
+	}
+	return d.ScoringSystem().BallotSize(eligible)
+}
+
+// hasFullBallot reports whether voterID cast a full ballot.
+func (d Battle) hasFullBallot(voterID string, ballots map[string]Votes) bool {
+	size := d.FullBallotSize(voterID)
+	v, ok := ballots[voterID]
+	if !ok {
+		return size == 0
+	}
+	count := 0
+	for _, e := range d.Entries {
+		if v.Scores[e.ID] != 0 && !e.IsEntrant(voterID) {
+			count++
+		}
+	}
+	return count >= size
+}
+
+// participationViolations returns the ids of the entries whose entrant did
+// not cast a full ballot. An entry is only checked against the voter id of
+// its entrant, entries without one are not checked.
+func (d Battle) participationViolations(votes []Votes) map[string]bool {
+	if !d.Participation.Enabled() {
+		return nil
+	}
+	ballots := make(map[string]Votes, len(votes))
+	for _, v := range votes {
+		ballots[v.VoterID] = v
+	}
+	res := make(map[string]bool)
+	for _, e := range d.Entries {
+		voterID := e.EntrantVoterID()
+		if voterID != "" && !d.hasFullBallot(voterID, ballots) {
+			res[e.ID] = true
+		}
+	}
+	return res
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var InvalidTransition = errors.New("invalid phase transition")
+
+// Phase is the lifecycle state of a battle.
+type Phase string
+
+const (
+	// PhaseHidden battles are not listed and cannot be accessed.
+	PhaseHidden Phase = "hidden"
+	// PhaseSubmission battles are accepting entries.
+	PhaseSubmission Phase = "submission"
+	// PhaseListening battles can be listened to but not voted on.
+	PhaseListening Phase = "listening"
+	// PhaseVoting battles are open for voting.
+	PhaseVoting Phase = "voting"
+	// PhaseResults battles are closed and have their results published.
+	PhaseResults Phase = "results"
+)
+
+// Actors recorded for phase transitions not made through the api.
+const (
+	ActorScan      = "scan"
+	ActorScheduler = "scheduler"
+	ActorMigration = "migration"
+)
+
+var phaseTransitions = map[Phase][]Phase{
+	PhaseHidden:     {PhaseSubmission, PhaseListening, PhaseVoting, PhaseResults},
+	PhaseSubmission: {PhaseHidden, PhaseListening, PhaseVoting},
+	PhaseListening:  {PhaseHidden, PhaseSubmission, PhaseVoting},
+	PhaseVoting:     {PhaseHidden, PhaseListening, PhaseResults},
+	PhaseResults:    {PhaseHidden, PhaseVoting},
+}
+
+func (p Phase) Valid() bool {
+	_, ok := phaseTransitions[p]
+	return ok
+}
+
+// CanTransitionTo reports whether a battle can move from p to the phase to.
+func (p Phase) CanTransitionTo(to Phase) bool {
+	return slices.Contains(phaseTransitions[p], to)
+}
+
+// AllowsListening reports whether entries can be downloaded and played.
+func (p Phase) AllowsListening() bool {
+	return p == PhaseListening || p == PhaseVoting || p == PhaseResults
+}
+
+// PhaseTransition records a phase change of a battle.
+type PhaseTransition struct {
+	From  Phase     `yaml:"from" json:"from"`
+	To    Phase     `yaml:"to" json:"to"`
+	At    time.Time `yaml:"at" json:"at"`
+	Actor string    `yaml:"actor" json:"actor"`
+}
+
+// SetPhase moves the battle to a new phase and records the transition.
+// Setting the current phase again does nothing, a battle without a phase can
+// move to any phase.
+func (d *Battle) SetPhase(to Phase, actor string, now time.Time) error {
+	if d.Phase == to {
+		return nil
+	}
+	if !to.Valid() || (d.Phase != "" && !d.Phase.CanTransitionTo(to)) {
+		return fmt.Errorf("%w: %s to %s", InvalidTransition, d.Phase, to)
+	}
+	d.Transitions = append(d.Transitions, PhaseTransition{
+		From:  d.Phase,
+		To:    to,
+		At:    now,
+		Actor: actor,
+	})
+	d.Phase = to
+	return nil
+}
+
+// LastTransitionTo returns the time of the last transition to phase, the zero
+// time if the battle has never been in the phase.
+func (d Battle) LastTransitionTo(phase Phase) time.Time {
+	for i := len(d.Transitions) - 1; i >= 0; i-- {
+		if d.Transitions[i].To == phase {
+			return d.Transitions[i].At
+		}
+	}
+	return time.Time{}
+}
+
+// ClosedAt returns when the results of the battle were published, the zero
+// time if the battle is not in the results phase.
+func (d Battle) ClosedAt() time.Time {
+	if d.Phase != PhaseResults {
+		return time.Time{}
+	}
+	return d.LastTransitionTo(PhaseResults)
+}
+
+// previousVisiblePhase returns the phase the battle was in before it was last
+// hidden.
+func (d Battle) previousVisiblePhase() Phase {
+	for i := len(d.Transitions) - 1; i >= 0; i-- {
+		if t := d.Transitions[i]; t.To == PhaseHidden && t.From != "" && t.From != PhaseHidden {
+			return t.From
+		}
+	}
+	return PhaseVoting
+}
+
+// UnmarshalYAML migrates battles stored with the hidden flag and closed_at
+// time to phases.
+func (d *Battle) UnmarshalYAML(value *yaml.Node) error {
+	type battle Battle
+	var stored struct {
+		battle         `yaml:",inline"`
+		LegacyHidden   bool      `yaml:"hidden"`
+		LegacyClosedAt time.Time `yaml:"closed_at"`
+	}
+	if err := value.Decode(&stored); err != nil {
+		return err
+	}
+	*d = Battle(stored.battle)
+	if d.Phase != "" {
+		return nil
+	}
+	d.Phase = PhaseVoting
+	if !stored.LegacyClosedAt.IsZero() {
+		d.Transitions = append(d.Transitions, PhaseTransition{
+			From:  PhaseVoting,
+			To:    PhaseResults,
+			At:    stored.LegacyClosedAt,
+			Actor: ActorMigration,
+		})
+		d.Phase = PhaseResults
+	}
+	if stored.LegacyHidden {
+		d.Transitions = append(d.Transitions, PhaseTransition{
+			From:  d.Phase,
+			To:    PhaseHidden,
+			At:    d.CreatedAt,
+			Actor: ActorMigration,
+		})
+		d.Phase = PhaseHidden
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/some-programs/battlr/pkg/rating"
+)
+
+// RatingPoint is the rating of an artist after a battle.
+type RatingPoint struct {
+	Battle   BattleRef
+	ClosedAt time.Time
+	Rating   rating.Rating
+}
+
+// ArtistRatings computes the Glicko-2 ratings of all artists, keyed by artist
+// key. Battles with published results are rated in the order they were
+// closed, the final order of a battle counts as a win against every entry
+// placed lower. Entries without a score are all tied. The ratings are
+// computed from the stored battles and votes on every call so reopened
+// battles and changed votes are always reflected.
+func (db *DB) ArtistRatings() (map[string][]RatingPoint, error) {
+	aliases, err := db.GetAliases()
+	if err != nil {
+		return nil, err
+	}
+	allBattles, err := db.GetAllBattles()
+	if err != nil {
+		return nil, err
+	}
+	var battles []Battle
+	for _, b := range allBattles {
+		if b.Phase == PhaseResults && !b.Reveal.Active {
+			battles = append(battles, b)
+		}
+	}
+	slices.SortFunc(battles, func(a, b Battle) int {
+		if v := a.ClosedAt().Compare(b.ClosedAt()); v != 0 {
+			return v
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	var periods []rating.Period
+	byName := make(map[string]Battle)
+	for _, battle := range battles {
+		votes, err := db.GetAllVotes(battle.Name)
+		if err != nil {
+			return nil, err
+		}
+		if len(votes) == 0 {
+			continue
+		}
+		var groups [][]string
+		var unscored []string
+		system := battle.ScoringSystem()
+		for _, place := range battle.Results(votes).Places {
+			var group []string
+			for _, e := range place.Entries {
+				group = append(group, aliases.Key(e.Author))
+			}
+			if !place.Placed(system) {
+				unscored = append(unscored, group...)
+				continue
+			}
+			groups = append(groups, group)
+		}
+		if len(unscored) > 0 {
+			groups = append(groups, unscored)
+		}
+		if len(groups) < 2 {
+			continue
+		}
+		periods = append(periods, rating.Period{ID: battle.Name, Groups: groups})
+		byName[battle.Name] = battle
+	}
+
+	history := rating.Compute(periods, rating.DefaultTau)
+	res := make(map[string][]RatingPoint, len(history))
+	for artist, points := range history {
+		for _, p := range points {
+			battle := byName[p.Period]
+			res[artist] = append(res[artist], RatingPoint{
+				Battle:   BattleRef{Name: battle.Name, Title: battle.DisplayName()},
+				ClosedAt: battle.ClosedAt(),
+				Rating:   p.Rating,
+			})
+		}
+	}
+	return res, nil
+}
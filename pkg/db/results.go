@@ -0,0 +1,60 @@
+package db
+
+import "slices"
+
+// Results is the outcome of a battle computed from its ballots.
+type Results struct {
+	Scores ScoreMap
+	Places Places
+	Stats  TieBreakStats
+	// Roles are the separate rankings of each voter role in weighted
+	// battles, Scores are then combined from them.
+	Roles []RoleResults
+	// Penalized are the ids of the entries whose score was lowered by the
+	// participation rule.
+	Penalized map[string]bool
+	// Disqualified are the entries left out of Places by the participation
+	// rule.
+	Disqualified Entries
+}
+
+// Results tallies the votes with the battle scoring system and orders the
+// entries into places.
+func (d Battle) Results(votes []Votes) Results {
+	system := d.ScoringSystem()
+	entries := slices.Clone(d.Entries)
+	res := Results{
+		Scores: system.Tally(entries, votes),
+		Stats:  NewTieBreakStats(system, len(entries), votes),
+	}
+	if d.Weighted() {
+		res.Roles = d.roleResults(system, entries, votes)
+	}
+	if violations := d.participationViolations(votes); len(violations) > 0 {
+		switch d.Participation.Rule {
+		case ParticipationPenalty:
+			res.Penalized = violations
+			// weighted battles cannot use the penalty rule.
+			for id := range violations {
+				res.Scores[id] -= d.Participation.Penalty
+			}
+		case ParticipationDisqualify:
+			entries = slices.DeleteFunc(entries, func(e Entry) bool {
+				if violations[e.ID] {
+					res.Disqualified = append(res.Disqualified, e)
+					return true
+				}
+				return false
+			})
+			res.Disqualified.SortByName()
+		}
+	}
+	if d.Weighted() {
+		for i, r := range res.Roles {
+			res.Roles[i].Places = entries.Places(r.Scores, d.TieBreakRules(), r.Stats)
+		}
+		res.Scores = combineRoles(res.Roles, entries)
+	}
+	res.Places = entries.Places(res.Scores, d.TieBreakRules(), res.Stats)
+	return res
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/some-programs/battlr/pkg/events"
+)
+
+var RevealNotAllowed = errors.New("results cannot be revealed")
+
+// Reveal is the state of a live reveal of the results where places are
+// published one at a time from the last place to the first.
+type Reveal struct {
+	// Active is set while a reveal is in progress, only the last Revealed
+	// places are published.
+	Active   bool `yaml:"active" json:"active"`
+	Revealed int  `yaml:"revealed" json:"revealed"`
+}
+
+// RevealAction changes the reveal state.
+type RevealAction string
+
+const (
+	// RevealStart starts a reveal with all places hidden.
+	RevealStart RevealAction = "start"
+	// RevealNext reveals the next place.
+	RevealNext RevealAction = "next"
+	// RevealBack hides the last revealed place again.
+	RevealBack RevealAction = "back"
+	// RevealEnd ends the reveal and publishes all results.
+	RevealEnd RevealAction = "end"
+)
+
+// Hidden returns how many of places are not revealed yet.
+func (r Reveal) Hidden(places int) int {
+	if !r.Active {
+		return 0
+	}
+	return max(places-r.Revealed, 0)
+}
+
+// apply applies an action to the reveal of a battle with the given number of
+// places. A reveal can be started before voting closes so that the results
+// stay hidden when the battle is closed, places are revealed only after it.
+func (r *Reveal) apply(action RevealAction, phase Phase, places int) error {
+	switch action {
+	case RevealStart:
+		if phase != PhaseVoting && phase != PhaseResults {
+			return fmt.Errorf("%w: battle is in %s phase", RevealNotAllowed, phase)
+		}
+		*r = Reveal{Active: true}
+	case RevealNext, RevealBack:
+		if !r.Active {
+			return fmt.Errorf("%w: reveal is not started", RevealNotAllowed)
+		}
+		if phase != PhaseResults {
+			return fmt.Errorf("%w: battle is in %s phase", RevealNotAllowed, phase)
+		}
+		if action == RevealNext {
+			r.Revealed = min(r.Revealed+1, places)
+		} else {
+			r.Revealed = max(r.Revealed-1, 0)
+		}
+	case RevealEnd:
+		*r = Reveal{}
+	default:
+		return fmt.Errorf("%w: unknown action %s", RevealNotAllowed, action)
+	}
+	return nil
+}
+
+// UpdateReveal applies an action to the reveal state of a battle with the
+// given number of places and notifies subscribers.
+func (db *DB) UpdateReveal(battleName string, action RevealAction, places int) (Reveal, error) {
+	var reveal Reveal
+	err := db.updateBattle(battleName, func(battle *Battle) error {
+		if err := battle.Reveal.apply(action, battle.Phase, places); err != nil {
+			return err
+		}
+		reveal = battle.Reveal
+		return nil
+	})
+	if err != nil {
+		return reveal, err
+	}
+	db.Events.Publish(events.Event{
+		Type:     events.Revealed,
+		Battle:   battleName,
+		Revealed: reveal.Revealed,
+	})
+	return reveal, nil
+}
@@ -0,0 +1,354 @@
+package db
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+	bolt "go.etcd.io/bbolt"
+)
+
+const settingsBucketName = "settings"
+
+var ipSaltKey = []byte("ip_salt")
+
+// Thresholds of the ballot review heuristics.
+const (
+	// FreshCookieAge is the largest age of a cookie when its ballot was
+	// created for the ballot to count as cast with a fresh cookie.
+	FreshCookieAge = time.Hour
+	// SharedAddressBallots is the number of ballots with fresh cookies from
+	// one address which are reported.
+	SharedAddressBallots = 3
+	// IdenticalBallotWindow is how close in time identical ballots have to
+	// be changed to be reported.
+	IdenticalBallotWindow = time.Minute
+	// MinListenTime is the shortest time between the first download of an
+	// entry and the creation of a ballot which is not reported.
+	MinListenTime = time.Minute
+)
+
+// BallotMeta describes the requests which cast a ballot, it is used to find
+// ballot stuffing.
+type BallotMeta struct {
+	// IPHash and UserAgent are from the request which created the ballot,
+	// the address is only stored as a salted hash.
+	IPHash    string `yaml:"ip_hash"`
+	UserAgent string `yaml:"user_agent"`
+	// FirstListen is when the client first downloaded an entry of the
+	// battle, zero if it had not when voting.
+	FirstListen time.Time `yaml:"first_listen"`
+	// Unheard are the entries voted for before the client downloaded them.
+	Unheard []string `yaml:"unheard,omitempty"`
+}
+
+// recorded reports whether the ballot was cast after metadata was recorded.
+func (m BallotMeta) recorded() bool {
+	return m.IPHash != "" || m.UserAgent != ""
+}
+
+// BallotRequest is the metadata of a request changing a ballot.
+type BallotRequest struct {
+	IPHash    string
+	UserAgent string
+	// Listens are the downloads of the client casting the vote.
+	Listens Listens
+}
+
+// record updates the metadata with a request giving score to entryID.
+func (m *BallotMeta) record(req BallotRequest, entryID string, score int) {
+	if !m.recorded() {
+		m.IPHash = req.IPHash
+		m.UserAgent = req.UserAgent
+	}
+	if m.FirstListen.IsZero() {
+		m.FirstListen = req.Listens.First()
+	}
+	m.Unheard = slices.DeleteFunc(m.Unheard, func(id string) bool { return id == entryID })
+	if _, ok := req.Listens.Entries[entryID]; score != 0 && !ok {
+		m.Unheard = append(m.Unheard, entryID)
+	}
+}
+
+// HashIP returns a salted hash of a client address so that ballots from the
+// same address can be grouped without storing the address. The salt is
+// created on first use and stays in the database.
+func (db *DB) HashIP(ip string) (string, error) {
+	if ip == "" {
+		return "", nil
+	}
+	var salt []byte
+	err := db.BoltDB.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket([]byte(settingsBucketName)); bucket != nil {
+			salt = slices.Clone(bucket.Get(ipSaltKey))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if salt == nil {
+		err = db.BoltDB.Update(func(tx *bolt.Tx) error {
+			bucket, err := tx.CreateBucketIfNotExists([]byte(settingsBucketName))
+			if err != nil {
+				return err
+			}
+			if salt = slices.Clone(bucket.Get(ipSaltKey)); salt != nil {
+				return nil
+			}
+			salt = make([]byte, 32)
+			if _, err := rand.Read(salt); err != nil {
+				return err
+			}
+			return bucket.Put(ipSaltKey, salt)
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	sum := sha256.Sum256(append(salt, ip...))
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// SetBallotExcluded excludes the ballot of a voter from the tally or counts
+// it again. Excluded ballots are kept for review.
+func (db *DB) SetBallotExcluded(battleName string, voterID string, excluded bool) error {
+	return db.BoltDB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(newVotesBucketKey(battleName))
+		if bucket == nil {
+			return NotFound
+		}
+		votes, err := getVotes(bucket, battleName, voterID)
+		if err != nil {
+			return err
+		}
+		if votes == nil {
+			return NotFound
+		}
+		votes.Excluded = excluded
+		return putVotes(bucket, *votes)
+	})
+}
+
+// SuspicionReason is a heuristic which found a group of ballots.
+type SuspicionReason string
+
+const (
+	// SuspicionSharedAddress groups ballots cast with fresh cookies from one
+	// address.
+	SuspicionSharedAddress SuspicionReason = "shared_address"
+	// SuspicionIdenticalBallots groups identical ballots changed within
+	// IdenticalBallotWindow of each other.
+	SuspicionIdenticalBallots SuspicionReason = "identical_ballots"
+	// SuspicionNoListening groups ballots cast without listening to the
+	// entries.
+	SuspicionNoListening SuspicionReason = "no_listening"
+)
+
+// Description returns a human readable description of the reason.
+func (r SuspicionReason) Description() string {
+	switch r {
+	case SuspicionSharedAddress:
+		return fmt.Sprintf("%d or more ballots cast with fresh cookies from the same address", SharedAddressBallots)
+	case SuspicionIdenticalBallots:
+		return fmt.Sprintf("identical ballots changed within %s of each other", IdenticalBallotWindow)
+	case SuspicionNoListening:
+		return fmt.Sprintf("ballots for entries which were not downloaded or cast within %s of the first download", MinListenTime)
+	}
+	return string(r)
+}
+
+// ReviewBallot is a ballot with the metadata used to judge it.
+type ReviewBallot struct {
+	VoterID   string    `json:"voter_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Scores    ScoreMap  `json:"scores"`
+	IPHash    string    `json:"ip_hash"`
+	UserAgent string    `json:"user_agent"`
+	// CookieAge is the age in seconds of the voter cookie when the ballot
+	// was created, it is left out for voters without a cookie id.
+	CookieAge *int64 `json:"cookie_age,omitempty"`
+	// ListenTime is the time in seconds between the first download and the
+	// creation of the ballot, it is left out if the voter did not download
+	// anything.
+	ListenTime *int64 `json:"listen_time,omitempty"`
+	// Unheard are the entries on the ballot which were voted for before
+	// they were downloaded.
+	Unheard  []string `json:"unheard"`
+	Excluded bool     `json:"excluded"`
+}
+
+func newReviewBallot(v Votes) ReviewBallot {
+	rb := ReviewBallot{
+		VoterID:   v.VoterID,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
+		Scores:    v.Scores,
+		IPHash:    v.Meta.IPHash,
+		UserAgent: v.Meta.UserAgent,
+		Unheard:   []string{},
+		Excluded:  v.Excluded,
+	}
+	if age, ok := cookieAge(v); ok {
+		seconds := int64(age / time.Second)
+		rb.CookieAge = &seconds
+	}
+	if !v.Meta.FirstListen.IsZero() {
+		seconds := int64(v.CreatedAt.Sub(v.Meta.FirstListen) / time.Second)
+		rb.ListenTime = &seconds
+	}
+	for _, id := range v.Meta.Unheard {
+		if v.Scores[id] != 0 {
+			rb.Unheard = append(rb.Unheard, id)
+		}
+	}
+	return rb
+}
+
+// cookieAge returns the age of the voter cookie when the ballot was created,
+// cookie ids are xids which include their creation time.
+func cookieAge(v Votes) (time.Duration, bool) {
+	id, ok := strings.CutPrefix(v.VoterID, "cookie:")
+	if !ok {
+		return 0, false
+	}
+	parsed, err := xid.FromString(id)
+	if err != nil {
+		return 0, false
+	}
+	return v.CreatedAt.Sub(parsed.Time()), true
+}
+
+// SuspiciousGroup is a group of ballots found by one heuristic.
+type SuspiciousGroup struct {
+	Reason      SuspicionReason `json:"reason"`
+	Description string          `json:"description"`
+	Ballots     []ReviewBallot  `json:"ballots"`
+}
+
+// BallotReview is the report of suspicious ballots of a battle.
+type BallotReview struct {
+	Battle     string            `json:"battle"`
+	NumBallots int               `json:"num_ballots"`
+	Excluded   int               `json:"excluded"`
+	Groups     []SuspiciousGroup `json:"groups"`
+}
+
+// ReviewBallots groups the suspicious ballots of a battle, excluded ballots
+// are included.
+func (db *DB) ReviewBallots(battleName string) (BallotReview, error) {
+	ballots, err := db.GetAllBallots(battleName)
+	if err != nil {
+		return BallotReview{}, err
+	}
+	review := BallotReview{
+		Battle: battleName,
+		Groups: suspiciousGroups(ballots),
+	}
+	for _, v := range ballots {
+		if !v.Cast() {
+			continue
+		}
+		review.NumBallots++
+		if v.Excluded {
+			review.Excluded++
+		}
+	}
+	return review, nil
+}
+
+func suspiciousGroups(ballots []Votes) []SuspiciousGroup {
+	var cast []Votes
+	for _, v := range ballots {
+		if v.Cast() && v.Meta.recorded() {
+			cast = append(cast, v)
+		}
+	}
+	slices.SortFunc(cast, func(a, b Votes) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	groups := []SuspiciousGroup{}
+	add := func(reason SuspicionReason, votes []Votes) {
+		group := SuspiciousGroup{Reason: reason, Description: reason.Description()}
+		for _, v := range votes {
+			group.Ballots = append(group.Ballots, newReviewBallot(v))
+		}
+		groups = append(groups, group)
+	}
+
+	byAddress := make(map[string][]Votes)
+	var addresses []string
+	for _, v := range cast {
+		if age, ok := cookieAge(v); !ok || age > FreshCookieAge || v.Meta.IPHash == "" {
+			continue
+		}
+		if _, ok := byAddress[v.Meta.IPHash]; !ok {
+			addresses = append(addresses, v.Meta.IPHash)
+		}
+		byAddress[v.Meta.IPHash] = append(byAddress[v.Meta.IPHash], v)
+	}
+	for _, address := range addresses {
+		if votes := byAddress[address]; len(votes) >= SharedAddressBallots {
+			add(SuspicionSharedAddress, votes)
+		}
+	}
+
+	byScores := make(map[string][]Votes)
+	var keys []string
+	for _, v := range cast {
+		key := scoresKey(v.Scores)
+		if _, ok := byScores[key]; !ok {
+			keys = append(keys, key)
+		}
+		byScores[key] = append(byScores[key], v)
+	}
+	for _, key := range keys {
+		votes := byScores[key]
+		slices.SortFunc(votes, func(a, b Votes) int {
+			return a.UpdatedAt.Compare(b.UpdatedAt)
+		})
+		start := 0
+		for i := 1; i <= len(votes); i++ {
+			if i < len(votes) && votes[i].UpdatedAt.Sub(votes[i-1].UpdatedAt) <= IdenticalBallotWindow {
+				continue
+			}
+			if i-start > 1 {
+				add(SuspicionIdenticalBallots, votes[start:i])
+			}
+			start = i
+		}
+	}
+
+	var unheard []Votes
+	for _, v := range cast {
+		rb := newReviewBallot(v)
+		if rb.ListenTime == nil || *rb.ListenTime < int64(MinListenTime/time.Second) || len(rb.Unheard) > 0 {
+			unheard = append(unheard, v)
+		}
+	}
+	if len(unheard) > 0 {
+		add(SuspicionNoListening, unheard)
+	}
+	return groups
+}
+
+// scoresKey returns the same string for identical ballots.
+func scoresKey(scores ScoreMap) string {
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	var b strings.Builder
+	for _, id := range ids {
+		fmt.Fprintf(&b, "%s=%d;", id, scores[id])
+	}
+	return b.String()
+}
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// RolePublic is the role of voters who were not given a role.
+const RolePublic = "public"
+
+// WeightedScoreScale is the combined score of an entry getting all points of
+// every role, weighted scores are in hundredths of a percent.
+const WeightedScoreScale = 10000
+
+var InvalidRole = errors.New("invalid voter role")
+
+// RoleWeights maps voter roles to their weight in the combined score, for
+// example a judge panel and the public. Weights are relative, a role no
+// voter of has voted is left out.
+type RoleWeights map[string]int
+
+func (w RoleWeights) Validate() error {
+	total := 0
+	for role, weight := range w {
+		if role == "" {
+			return fmt.Errorf("%w: empty role name", InvalidRole)
+		}
+		if weight < 0 {
+			return fmt.Errorf("%w: negative weight for %s", InvalidRole, role)
+		}
+		total += weight
+	}
+	if len(w) > 0 && total == 0 {
+		return fmt.Errorf("%w: all weights are 0", InvalidRole)
+	}
+	return nil
+}
+
+// Names returns the roles ordered by name with RolePublic last.
+func (w RoleWeights) Names() []string {
+	var names []string
+	for role := range w {
+		if role != RolePublic {
+			names = append(names, role)
+		}
+	}
+	slices.Sort(names)
+	if _, ok := w[RolePublic]; ok {
+		names = append(names, RolePublic)
+	}
+	return names
+}
+
+// Weighted reports whether the ballots are tallied per role.
+func (d Battle) Weighted() bool {
+	return len(d.Roles) > 0
+}
+
+// VoterRole returns the role of the voter, RolePublic if the voter has no
+// role or a role the battle does not weigh.
+func (d Battle) VoterRole(voterID string) string {
+	role, ok := d.VoterRoles[voterID]
+	if _, weighted := d.Roles[role]; !ok || !weighted {
+		return RolePublic
+	}
+	return role
+}
+
+// RoleResults is the ranking of the entries by the ballots of one role.
+type RoleResults struct {
+	Role   string
+	Weight int
+	// NumVoters is the number of ballots cast by voters with the role.
+	NumVoters int
+	Scores    ScoreMap
+	Places    Places
+	Stats     TieBreakStats
+	// Share is the part of the combined score decided by the role, 0 if
+	// the ballots of the role gave no points.
+	Share float64
+}
+
+// roleResults tallies the ballots of each role separately.
+func (d Battle) roleResults(system ScoringSystem, entries Entries, votes []Votes) []RoleResults {
+	byRole := make(map[string][]Votes)
+	for _, v := range votes {
+		role := d.VoterRole(v.VoterID)
+		byRole[role] = append(byRole[role], v)
+	}
+	var res []RoleResults
+	for _, role := range d.Roles.Names() {
+		res = append(res, RoleResults{
+			Role:      role,
+			Weight:    d.Roles[role],
+			NumVoters: len(byRole[role]),
+			Scores:    system.Tally(entries, byRole[role]),
+			Stats:     NewTieBreakStats(system, len(entries), byRole[role]),
+		})
+	}
+	return res
+}
+
+// combineRoles returns the combined score of each entry and sets the share
+// of each role. Every role contributes its share of the weight times the
+// share of the points of the role the entry got, so the number of voters in a
+// role does not matter.
+func combineRoles(roles []RoleResults, entries Entries) ScoreMap {
+	totals := make([]int, len(roles))
+	totalWeight := 0
+	for i, r := range roles {
+		for _, e := range entries {
+			totals[i] += max(r.Scores[e.ID], 0)
+		}
+		if totals[i] > 0 {
+			totalWeight += r.Weight
+		}
+	}
+	scores := make(ScoreMap)
+	if totalWeight == 0 {
+		return scores
+	}
+	for i, r := range roles {
+		if totals[i] > 0 {
+			roles[i].Share = float64(r.Weight) / float64(totalWeight)
+		}
+	}
+	for _, e := range entries {
+		var score float64
+		for i, r := range roles {
+			if totals[i] > 0 {
+				score += r.Share * float64(r.Scores[e.ID]) / float64(totals[i])
+			}
+		}
+		scores[e.ID] = int(math.Round(score * WeightedScoreScale))
+	}
+	return scores
+}
+
+// SetVoterRole gives the voter a role in the battle, an empty role makes the
+// voter part of the public again.
+func (db *DB) SetVoterRole(battleName string, voterID string, role string) error {
+	return db.updateBattle(battleName, func(battle *Battle) error {
+		if role == "" || role == RolePublic {
+			delete(battle.VoterRoles, voterID)
+			return nil
+		}
+		if _, ok := battle.Roles[role]; !ok {
+			return fmt.Errorf("%w: %s", InvalidRole, role)
+		}
+		if battle.VoterRoles == nil {
+			battle.VoterRoles = make(map[string]string)
+		}
+		battle.VoterRoles[voterID] = role
+		return nil
+	})
+}
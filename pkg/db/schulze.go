@@ -0,0 +1,86 @@
+package db
+
+// PairwiseMatrix holds the number of voters preferring one entry over
+// another, Prefer[i][j] is the number of voters ranking Entries[i] above
+// Entries[j].
+type PairwiseMatrix struct {
+	Entries Entries
+	Prefer  [][]int
+}
+
+// NewPairwiseMatrix counts pairwise preferences from ranked ballots. An entry
+// left off a ballot is ranked below all entries on it.
+func NewPairwiseMatrix(entries Entries, votes []Votes) PairwiseMatrix {
+	n := len(entries)
+	m := PairwiseMatrix{
+		Entries: entries,
+		Prefer:  make([][]int, n),
+	}
+	for i := range m.Prefer {
+		m.Prefer[i] = make([]int, n)
+	}
+	for _, v := range votes {
+		for i, a := range entries {
+			ra, okA := v.Scores[a.ID]
+			if !okA {
+				continue
+			}
+			for j, b := range entries {
+				if i == j {
+					continue
+				}
+				rb, okB := v.Scores[b.ID]
+				if !okB || ra < rb {
+					m.Prefer[i][j]++
+				}
+			}
+		}
+	}
+	return m
+}
+
+// StrongestPaths computes the Schulze strongest path strengths between all
+// pairs of entries using the winning votes measure.
+func (m PairwiseMatrix) StrongestPaths() [][]int {
+	n := len(m.Entries)
+	p := make([][]int, n)
+	for i := range p {
+		p[i] = make([]int, n)
+		for j := range p[i] {
+			if i != j && m.Prefer[i][j] > m.Prefer[j][i] {
+				p[i][j] = m.Prefer[i][j]
+			}
+		}
+	}
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			if i == k {
+				continue
+			}
+			for j := 0; j < n; j++ {
+				if j == i || j == k {
+					continue
+				}
+				p[i][j] = max(p[i][j], min(p[i][k], p[k][j]))
+			}
+		}
+	}
+	return p
+}
+
+// Schulze returns the number of entries each entry beats in the Schulze
+// method. The Schulze relation is transitive so ordering entries by this
+// count gives the Schulze ranking, with equal counts for tied entries.
+func (m PairwiseMatrix) Schulze() ScoreMap {
+	p := m.StrongestPaths()
+	res := make(ScoreMap)
+	for i, a := range m.Entries {
+		res[a.ID] = 0
+		for j := range m.Entries {
+			if i != j && p[i][j] > p[j][i] {
+				res[a.ID]++
+			}
+		}
+	}
+	return res
+}
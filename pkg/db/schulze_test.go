@@ -0,0 +1,211 @@
+package db
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func testEntries(ids ...string) Entries {
+	var res Entries
+	for _, id := range ids {
+		res = append(res, Entry{ID: id, Title: id})
+	}
+	return res
+}
+
+// rankedBallots returns count ballots ranking the entries in the order of
+// the letters of ranking, entries missing from ranking are left off.
+func rankedBallots(count int, ranking string) []Votes {
+	var res []Votes
+	for range count {
+		scores := make(ScoreMap)
+		for i, id := range strings.Split(ranking, "") {
+			scores[id] = i + 1
+		}
+		res = append(res, Votes{VoterID: fmt.Sprint(len(res)), Scores: scores})
+	}
+	return res
+}
+
+// wikipediaBallots is the example election from the Wikipedia article on the
+// Schulze method, the winner is E.
+func wikipediaBallots() []Votes {
+	return slices.Concat(
+		rankedBallots(5, "ACBED"),
+		rankedBallots(5, "ADECB"),
+		rankedBallots(8, "BEDAC"),
+		rankedBallots(3, "CABED"),
+		rankedBallots(7, "CAEBD"),
+		rankedBallots(2, "CBADE"),
+		rankedBallots(7, "DCEBA"),
+		rankedBallots(8, "EBADC"),
+	)
+}
+
+func TestPairwiseMatrix(t *testing.T) {
+	m := NewPairwiseMatrix(testEntries("A", "B", "C", "D", "E"), wikipediaBallots())
+	want := [][]int{
+		{0, 20, 26, 30, 22},
+		{25, 0, 16, 33, 18},
+		{19, 29, 0, 17, 24},
+		{15, 12, 28, 0, 14},
+		{23, 27, 21, 31, 0},
+	}
+	if !slices.EqualFunc(m.Prefer, want, slices.Equal) {
+		t.Errorf("prefer = %v, want %v", m.Prefer, want)
+	}
+}
+
+func TestPairwiseMatrixPartialBallot(t *testing.T) {
+	// entries left off a ballot are ranked below the entries on it and are
+	// not preferred over each other.
+	m := NewPairwiseMatrix(testEntries("A", "B", "C"), rankedBallots(1, "B"))
+	want := [][]int{
+		{0, 0, 0},
+		{1, 0, 1},
+		{0, 0, 0},
+	}
+	if !slices.EqualFunc(m.Prefer, want, slices.Equal) {
+		t.Errorf("prefer = %v, want %v", m.Prefer, want)
+	}
+}
+
+func TestStrongestPaths(t *testing.T) {
+	m := NewPairwiseMatrix(testEntries("A", "B", "C", "D", "E"), wikipediaBallots())
+	want := [][]int{
+		{0, 28, 28, 30, 24},
+		{25, 0, 28, 33, 24},
+		{25, 29, 0, 29, 24},
+		{25, 28, 28, 0, 24},
+		{25, 28, 28, 31, 0},
+	}
+	if got := m.StrongestPaths(); !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("strongest paths = %v, want %v", got, want)
+	}
+}
+
+func TestSchulze(t *testing.T) {
+	tests := []struct {
+		name  string
+		votes []Votes
+		want  ScoreMap
+	}{
+		{
+			name:  "wikipedia example",
+			votes: wikipediaBallots(),
+			want:  ScoreMap{"E": 4, "A": 3, "C": 2, "B": 1, "D": 0},
+		},
+		{
+			// A beats B beats C beats A with equal margins, no entry beats
+			// another.
+			name:  "condorcet cycle",
+			votes: slices.Concat(rankedBallots(1, "ABC"), rankedBallots(1, "BCA"), rankedBallots(1, "CAB")),
+			want:  ScoreMap{"A": 0, "B": 0, "C": 0},
+		},
+		{
+			name: "no ballots",
+			want: ScoreMap{"A": 0, "B": 0, "C": 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			for id := range tt.want {
+				ids = append(ids, id)
+			}
+			got := schulzeSystem{}.Tally(testEntries(ids...), tt.votes)
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("tally = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBorda(t *testing.T) {
+	tests := []struct {
+		name  string
+		votes []Votes
+		want  ScoreMap
+	}{
+		{
+			name:  "full ballots",
+			votes: slices.Concat(rankedBallots(2, "ABC"), rankedBallots(1, "CBA")),
+			want:  ScoreMap{"A": 7, "B": 6, "C": 5},
+		},
+		{
+			name:  "entries left off a ballot get no points",
+			votes: slices.Concat(rankedBallots(1, "B"), rankedBallots(1, "CA")),
+			want:  ScoreMap{"A": 2, "B": 3, "C": 3},
+		},
+		{
+			name:  "ranks out of range are ignored",
+			votes: []Votes{{Scores: ScoreMap{"A": 4, "B": 1, "C": -1}}},
+			want:  ScoreMap{"B": 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rankedSystem{}.Tally(testEntries("A", "B", "C"), tt.votes)
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("tally = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlacesTop(t *testing.T) {
+	points, _ := NewScoringSystem(ScoringConfig{})
+	schulze, _ := NewScoringSystem(ScoringConfig{System: ScoringSchulze})
+	entries := testEntries("A", "B", "C")
+	// the entries with a score other than 0 are on a ballot.
+	places := func(scores ScoreMap) Places {
+		stats := TieBreakStats{VotesReceived: make(map[string]int)}
+		for id, score := range scores {
+			if score != 0 {
+				stats.VotesReceived[id] = 1
+			}
+		}
+		return entries.Places(scores, nil, stats)
+	}
+	tests := []struct {
+		name   string
+		places Places
+		system ScoringSystem
+		n      int
+		want   int
+	}{
+		{"points", places(ScoreMap{"A": 3, "B": 2, "C": 1}), points, 3, 3},
+		{"points limited", places(ScoreMap{"A": 3, "B": 2, "C": 1}), points, 2, 2},
+		{"points without votes", places(ScoreMap{"A": 3, "B": 0, "C": 0}), points, 3, 1},
+		{"points penalized", places(ScoreMap{"A": 3, "B": 2, "C": -1}), points, 3, 3},
+		{"schulze last place", places(ScoreMap{"A": 2, "B": 1, "C": 0}), schulze, 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.places.Top(tt.n, tt.system); len(got) != tt.want {
+				t.Errorf("len(top) = %d, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestBordaUnrankedEntry(t *testing.T) {
+	battle := Battle{
+		Scoring: ScoringConfig{System: ScoringRanked},
+		Entries: testEntries("A", "B", "C"),
+	}
+	results := battle.Results(rankedBallots(2, "AB"))
+	if len(results.Places) != 3 {
+		t.Fatalf("places = %+v, want 3", results.Places)
+	}
+	last := results.Places[2]
+	if last.Entries[0].ID != "C" || last.Placed(battle.ScoringSystem()) {
+		t.Errorf("last place = %+v, want C without a place", last)
+	}
+	if top := results.Places.Top(3, battle.ScoringSystem()); len(top) != 2 {
+		t.Errorf("len(top) = %d, want 2", len(top))
+	}
+}
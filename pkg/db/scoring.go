@@ -0,0 +1,275 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// Scoring system names.
+const (
+	// ScoringTop3 is the default system, voters give 3, 2 and 1 points to
+	// three different entries.
+	ScoringTop3 = "top3"
+	// ScoringPoints is like ScoringTop3 with configurable point values.
+	ScoringPoints = "points"
+	// ScoringApproval lets voters approve any number of entries.
+	ScoringApproval = "approval"
+	// ScoringRating lets voters rate every entry from 1 to 10.
+	ScoringRating = "rating"
+	// ScoringRanked lets voters rank all entries, tallied as a Borda count.
+	ScoringRanked = "ranked"
+	// ScoringSchulze uses ranked ballots tallied with the Schulze method.
+	ScoringSchulze = "schulze"
+)
+
+const (
+	maxRating = 10
+)
+
+// ScoringConfig selects and configures the scoring system of a battle.
+type ScoringConfig struct {
+	System string `yaml:"system" json:"system"`
+	// Points are the point values available to voters in the ScoringPoints
+	// system.
+	Points []int `yaml:"points" json:"points,omitempty"`
+}
+
+// ScoringSystem defines which ballots are valid and how they are tallied.
+//
+// Ballots are stored as a ScoreMap of entry ID to a system specific value, 0
+// is never a valid value and means the entry is not on the ballot.
+type ScoringSystem interface {
+	Name() string
+	// Options returns the values a voter can give to an entry.
+	Options(numEntries int) []VoteOption
+	// Unique reports whether a value can be given to only one entry per
+	// ballot.
+	Unique() bool
+	// FirstPlace returns the best value a voter can give to an entry.
+	FirstPlace(numEntries int) int
+	// Validate returns InvalidScore if score is not a valid ballot value.
+	Validate(score int, numEntries int) error
+	// BallotSize returns the number of entries on a full ballot when
+	// numEntries entries can be voted for.
+	BallotSize(numEntries int) int
+	// RanksAll reports whether the tally places every entry, also the
+	// entries which are on no ballot.
+	RanksAll() bool
+	// Tally returns the total of each entry, a higher total is better.
+	Tally(entries Entries, votes []Votes) ScoreMap
+}
+
+// PairwiseScoringSystem is implemented by scoring systems which compare
+// entries pairwise.
+type PairwiseScoringSystem interface {
+	ScoringSystem
+	Pairwise(entries Entries, votes []Votes) PairwiseMatrix
+}
+
+// VoteOption is a value a voter can give to an entry.
+type VoteOption struct {
+	Value int
+	Label string
+}
+
+// NewScoringSystem returns the scoring system described by config, the
+// zero value returns the ScoringTop3 system.
+func NewScoringSystem(config ScoringConfig) (ScoringSystem, error) {
+	switch config.System {
+	case "", ScoringTop3:
+		return pointsSystem{name: ScoringTop3, points: []int{3, 2, 1}}, nil
+	case ScoringPoints:
+		if len(config.Points) == 0 {
+			return nil, fmt.Errorf("scoring system %s: no points configured", config.System)
+		}
+		points := slices.Clone(config.Points)
+		slices.Sort(points)
+		slices.Reverse(points)
+		if points[len(points)-1] <= 0 {
+			return nil, fmt.Errorf("scoring system %s: points must be positive", config.System)
+		}
+		if len(slices.Compact(slices.Clone(points))) != len(points) {
+			return nil, fmt.Errorf("scoring system %s: points must be unique", config.System)
+		}
+		return pointsSystem{name: ScoringPoints, points: points}, nil
+	case ScoringApproval:
+		return approvalSystem{}, nil
+	case ScoringRating:
+		return ratingSystem{}, nil
+	case ScoringRanked:
+		return rankedSystem{}, nil
+	case ScoringSchulze:
+		return schulzeSystem{}, nil
+	}
+	return nil, fmt.Errorf("unknown scoring system: %s", config.System)
+}
+
+// pointsSystem gives each point value to at most one entry per ballot and
+// sums the points.
+type pointsSystem struct {
+	name   string
+	points []int
+}
+
+func (s pointsSystem) Name() string { return s.name }
+
+func (s pointsSystem) Options(numEntries int) []VoteOption {
+	options := make([]VoteOption, 0, len(s.points))
+	for i := len(s.points) - 1; i >= 0; i-- {
+		p := s.points[i]
+		label := strconv.Itoa(p) + " points"
+		if p == 1 {
+			label = "1 point"
+		}
+		options = append(options, VoteOption{Value: p, Label: label})
+	}
+	return options
+}
+
+func (s pointsSystem) Unique() bool { return true }
+
+func (s pointsSystem) FirstPlace(numEntries int) int { return s.points[0] }
+
+func (s pointsSystem) Validate(score int, numEntries int) error {
+	if !slices.Contains(s.points, score) {
+		return InvalidScore
+	}
+	return nil
+}
+
+func (s pointsSystem) BallotSize(numEntries int) int {
+	return min(len(s.points), numEntries)
+}
+
+func (s pointsSystem) RanksAll() bool { return false }
+
+func (s pointsSystem) Tally(entries Entries, votes []Votes) ScoreMap {
+	return SumScores(votes)
+}
+
+// approvalSystem counts the voters approving each entry.
+type approvalSystem struct{}
+
+func (s approvalSystem) Name() string { return ScoringApproval }
+
+func (s approvalSystem) Options(numEntries int) []VoteOption {
+	return []VoteOption{{Value: 1, Label: "approve"}}
+}
+
+func (s approvalSystem) Unique() bool { return false }
+
+func (s approvalSystem) FirstPlace(numEntries int) int { return 1 }
+
+func (s approvalSystem) Validate(score int, numEntries int) error {
+	if score != 1 {
+		return InvalidScore
+	}
+	return nil
+}
+
+// BallotSize of an approval ballot is one approval, leaving entries
+// unapproved is a vote against them.
+func (s approvalSystem) BallotSize(numEntries int) int {
+	return min(1, numEntries)
+}
+
+func (s approvalSystem) RanksAll() bool { return false }
+
+func (s approvalSystem) Tally(entries Entries, votes []Votes) ScoreMap {
+	return SumScores(votes)
+}
+
+// ratingSystem sums ratings from 1 to maxRating given to each entry.
+type ratingSystem struct{}
+
+func (s ratingSystem) Name() string { return ScoringRating }
+
+func (s ratingSystem) Options(numEntries int) []VoteOption {
+	options := make([]VoteOption, 0, maxRating)
+	for i := 1; i <= maxRating; i++ {
+		options = append(options, VoteOption{Value: i, Label: strconv.Itoa(i)})
+	}
+	return options
+}
+
+func (s ratingSystem) Unique() bool { return false }
+
+func (s ratingSystem) FirstPlace(numEntries int) int { return maxRating }
+
+func (s ratingSystem) Validate(score int, numEntries int) error {
+	if score < 1 || score > maxRating {
+		return InvalidScore
+	}
+	return nil
+}
+
+func (s ratingSystem) BallotSize(numEntries int) int { return numEntries }
+
+func (s ratingSystem) RanksAll() bool { return false }
+
+func (s ratingSystem) Tally(entries Entries, votes []Votes) ScoreMap {
+	return SumScores(votes)
+}
+
+// rankedSystem stores the rank given to each entry, 1 is the best, and
+// tallies the ballots as a Borda count. Entries left off a ballot get no
+// points from it.
+type rankedSystem struct{}
+
+func (s rankedSystem) Name() string { return ScoringRanked }
+
+func (s rankedSystem) Options(numEntries int) []VoteOption {
+	options := make([]VoteOption, 0, numEntries)
+	for i := 1; i <= numEntries; i++ {
+		options = append(options, VoteOption{Value: i, Label: "#" + strconv.Itoa(i)})
+	}
+	return options
+}
+
+func (s rankedSystem) Unique() bool { return true }
+
+func (s rankedSystem) FirstPlace(numEntries int) int { return 1 }
+
+func (s rankedSystem) Validate(score int, numEntries int) error {
+	if score < 1 || score > numEntries {
+		return InvalidScore
+	}
+	return nil
+}
+
+func (s rankedSystem) BallotSize(numEntries int) int { return numEntries }
+
+func (s rankedSystem) RanksAll() bool { return false }
+
+func (s rankedSystem) Tally(entries Entries, votes []Votes) ScoreMap {
+	res := make(ScoreMap)
+	n := len(entries)
+	for _, v := range votes {
+		for id, rank := range v.Scores {
+			if rank < 1 || rank > n {
+				continue
+			}
+			res[id] += n - rank + 1
+		}
+	}
+	return res
+}
+
+// schulzeSystem uses the same ballots as rankedSystem and orders entries by
+// the Schulze method.
+type schulzeSystem struct {
+	rankedSystem
+}
+
+func (s schulzeSystem) Name() string { return ScoringSchulze }
+
+func (s schulzeSystem) RanksAll() bool { return true }
+
+func (s schulzeSystem) Tally(entries Entries, votes []Votes) ScoreMap {
+	return s.Pairwise(entries, votes).Schulze()
+}
+
+func (s schulzeSystem) Pairwise(entries Entries, votes []Votes) PairwiseMatrix {
+	return NewPairwiseMatrix(entries, votes)
+}
@@ -0,0 +1,256 @@
+package db
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+
+	bolt "go.etcd.io/bbolt"
+	"gopkg.in/yaml.v3"
+)
+
+const seasonsBucketName = "seasons"
+
+var InvalidSeason = errors.New("invalid season")
+
+// DefaultSeasonPoints are the points awarded for the first places of a
+// battle when a season does not set its own points table.
+var DefaultSeasonPoints = []int{10, 8, 6, 5, 4, 3, 2, 1}
+
+// Season groups battles into a shared leaderboard.
+type Season struct {
+	Name    string   `yaml:"name" json:"name"`
+	Title   string   `yaml:"title" json:"title"`
+	Battles []string `yaml:"battles" json:"battles"`
+	// Points are the points awarded for each place starting from the first,
+	// DefaultSeasonPoints is used if it is empty.
+	Points []int `yaml:"points" json:"points"`
+}
+
+// DisplayName returns the title of the season or its name if no title is
+// set.
+func (s Season) DisplayName() string {
+	if s.Title != "" {
+		return s.Title
+	}
+	return s.Name
+}
+
+// PointsTable returns the configured points or the default points.
+func (s Season) PointsTable() []int {
+	if len(s.Points) == 0 {
+		return DefaultSeasonPoints
+	}
+	return s.Points
+}
+
+func (s Season) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("%w: name is required", InvalidSeason)
+	}
+	for _, p := range s.Points {
+		if p < 0 {
+			return fmt.Errorf("%w: negative points: %d", InvalidSeason, p)
+		}
+	}
+	return nil
+}
+
+// Standing is the season result of one author.
+type Standing struct {
+	Author    string           `json:"author"`
+	ArtistKey string           `json:"artist_key"`
+	Points    int              `json:"points"`
+	Wins      int              `json:"wins"`
+	Results   []StandingResult `json:"results"`
+}
+
+// StandingResult is the placement of an author in one battle.
+type StandingResult struct {
+	Battle string `json:"battle"`
+	// Place is the 1-based place, 0 if the entry did not place.
+	Place  int `json:"place"`
+	Points int `json:"points"`
+}
+
+// Standings adds up the points of each author in the given battles. The
+// places of each battle are given in the same order as battles, places which
+// are not Placed are not awarded points. With publicPlaces above 0 only that
+// many places of each battle are awarded. Standings are ordered by points, then
+// by wins.
+func (s Season) Standings(battles []Battle, places []Places, aliases Aliases, publicPlaces int) []Standing {
+	table := s.PointsTable()
+	byAuthor := make(map[string]*Standing)
+	var keys []string
+	for i, battle := range battles {
+		for placeIdx, place := range places[i] {
+			points := 0
+			placeNum := 0
+			public := publicPlaces == 0 || placeIdx < publicPlaces
+			if public && place.Placed(battle.ScoringSystem()) {
+				placeNum = placeIdx + 1
+				if placeIdx < len(table) {
+					points = table[placeIdx]
+				}
+			}
+			for _, entry := range place.Entries {
+				key := aliases.Key(entry.Author)
+				st, ok := byAuthor[key]
+				if !ok {
+					st = &Standing{Author: entry.Author, ArtistKey: key}
+					if name, ok := aliases.artistName(key); ok {
+						st.Author = name
+					}
+					byAuthor[key] = st
+					keys = append(keys, key)
+				}
+				st.Points += points
+				if placeNum == 1 {
+					st.Wins++
+				}
+				st.Results = append(st.Results, StandingResult{
+					Battle: battle.Name,
+					Place:  placeNum,
+					Points: points,
+				})
+			}
+		}
+	}
+
+	standings := make([]Standing, 0, len(keys))
+	for _, key := range keys {
+		standings = append(standings, *byAuthor[key])
+	}
+	slices.SortStableFunc(standings, func(a, b Standing) int {
+		if v := cmp.Compare(b.Points, a.Points); v != 0 {
+			return v
+		}
+		if v := cmp.Compare(b.Wins, a.Wins); v != 0 {
+			return v
+		}
+		return cmp.Compare(a.ArtistKey, b.ArtistKey)
+	})
+	return standings
+}
+
+// ResultIn returns the result of the author in a battle, nil if the author
+// did not enter it.
+func (st Standing) ResultIn(battleName string) *StandingResult {
+	for _, r := range st.Results {
+		if r.Battle == battleName {
+			return &r
+		}
+	}
+	return nil
+}
+
+// SeasonResults are the standings of a season.
+type SeasonResults struct {
+	Season Season `json:"season"`
+	// Battles are the battles counted in the standings.
+	Battles   []BattleRef `json:"battles"`
+	Standings []Standing  `json:"standings"`
+}
+
+// BattleRef identifies a battle in listings.
+type BattleRef struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+}
+
+// SeasonResults computes the standings of a season from the battles which
+// have their results published. Battles with a reveal in progress are left
+// out. publicPlaces limits the awarded places, see Season.Standings.
+func (db *DB) SeasonResults(season Season, publicPlaces int) (SeasonResults, error) {
+	res := SeasonResults{Season: season}
+	aliases, err := db.GetAliases()
+	if err != nil {
+		return res, err
+	}
+	var (
+		battles []Battle
+		places  []Places
+	)
+	for _, name := range season.Battles {
+		battle, err := db.GetBattle(name)
+		if err != nil && !errors.Is(err, NotFound) {
+			return res, err
+		}
+		if battle == nil || battle.Phase != PhaseResults || battle.Reveal.Active {
+			continue
+		}
+		votes, err := db.GetAllVotes(name)
+		if err != nil {
+			return res, err
+		}
+		battles = append(battles, *battle)
+		places = append(places, battle.Results(votes).Places)
+		res.Battles = append(res.Battles, BattleRef{Name: battle.Name, Title: battle.DisplayName()})
+	}
+	res.Standings = season.Standings(battles, places, aliases, publicPlaces)
+	return res, nil
+}
+
+func (db *DB) GetSeason(name string) (*Season, error) {
+	var season *Season
+	err := db.BoltDB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(seasonsBucketName))
+		if bucket == nil {
+			return nil
+		}
+		var err error
+		season, err = retreiveYaml[Season](bucket, []byte(name))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return season, nil
+}
+
+func (db *DB) GetAllSeasons() ([]Season, error) {
+	var seasons []Season
+	err := db.BoltDB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(seasonsBucketName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var season Season
+			if err := yaml.Unmarshal(v, &season); err != nil {
+				return err
+			}
+			seasons = append(seasons, season)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return seasons, nil
+}
+
+// PutSeason creates or replaces a season.
+func (db *DB) PutSeason(season Season) error {
+	if err := season.validate(); err != nil {
+		return err
+	}
+	return db.BoltDB.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(seasonsBucketName))
+		if err != nil {
+			return err
+		}
+		return storeYaml(bucket, []byte(season.Name), season)
+	})
+}
+
+func (db *DB) DeleteSeason(name string) error {
+	return db.BoltDB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(seasonsBucketName))
+		if bucket == nil || bucket.Get([]byte(name)) == nil {
+			return NotFound
+		}
+		return bucket.Delete([]byte(name))
+	})
+}
@@ -0,0 +1,36 @@
+package db
+
+import "testing"
+
+func TestStandingsPublicPlaces(t *testing.T) {
+	battle := Battle{Name: "b"}
+	var places Places
+	for i, author := range []string{"A", "B", "C", "D"} {
+		places = append(places, Place{
+			Entries: Entries{{ID: author, Author: author}},
+			Score:   10 - i,
+			Voted:   true,
+		})
+	}
+	season := Season{Points: []int{4, 3, 2, 1}}
+
+	tests := []struct {
+		publicPlaces int
+		wantD        StandingResult
+	}{
+		{0, StandingResult{Battle: "b", Place: 4, Points: 1}},
+		{3, StandingResult{Battle: "b"}},
+	}
+	for _, tt := range tests {
+		standings := season.Standings([]Battle{battle}, []Places{places}, Aliases{}, tt.publicPlaces)
+		if len(standings) != 4 {
+			t.Fatalf("%d standings, want 4", len(standings))
+		}
+		if got := standings[2].Results[0]; got.Place != 3 || got.Points != 2 {
+			t.Errorf("public places %d: third = %+v, want place 3 with 2 points", tt.publicPlaces, got)
+		}
+		if got := *standings[3].ResultIn("b"); got != tt.wantD {
+			t.Errorf("public places %d: fourth = %+v, want %+v", tt.publicPlaces, got, tt.wantD)
+		}
+	}
+}
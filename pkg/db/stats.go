@@ -0,0 +1,214 @@
+package db
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// agreementBuckets is the number of histogram buckets of voter agreement.
+const agreementBuckets = 5
+
+// Stats describes how the voting of a battle went.
+type Stats struct {
+	NumVoters int
+	// Options are the ballot values, the distributions of entries are in
+	// the same order.
+	Options []VoteOption
+	// Entries are in the order of the results.
+	Entries   []EntryStats
+	Agreement AgreementStats
+	// MostPolarizing is the entry with the most spread out ballot points,
+	// nil if there are no votes.
+	MostPolarizing *EntryStats
+	Turnout        []TurnoutPoint
+}
+
+// EntryStats are the votes given to an entry.
+type EntryStats struct {
+	Entry Entry
+	Place int
+	// Distribution is the number of ballots giving each option value.
+	Distribution []int
+	// Voters is the number of ballots the entry is on.
+	Voters int
+	// Mean and Polarization are the mean and the standard deviation of the
+	// points given to the entry by each ballot, ballots without the entry
+	// give it no points.
+	Mean         float64
+	Polarization float64
+}
+
+// AgreementStats describes how well ballots agree with the final order. The
+// agreement of a ballot is the share of the entry pairs it orders which are
+// ordered the same way in the results.
+type AgreementStats struct {
+	Mean   float64
+	Median float64
+	// Histogram is the number of ballots by agreement in equal sized
+	// buckets from 0% to 100%.
+	Histogram []int
+	// Voters is the number of ballots ordering at least one pair.
+	Voters int
+}
+
+// TurnoutPoint is the voting activity in the period starting at Start.
+type TurnoutPoint struct {
+	Start time.Time
+	// New is the number of ballots started in the period.
+	New int
+	// Updated is the number of ballots last changed in the period.
+	Updated int
+	// Total is the number of ballots started up to the end of the period.
+	Total int
+}
+
+// Stats computes the voting statistics of the battle.
+func (d Battle) Stats(votes []Votes) Stats {
+	system := d.ScoringSystem()
+	results := d.Results(votes)
+	stats := Stats{
+		NumVoters: len(votes),
+		Options:   system.Options(len(d.Entries)),
+		Turnout:   turnout(votes),
+	}
+
+	// points given by each ballot alone
+	ballotPoints := make([]ScoreMap, len(votes))
+	for i, v := range votes {
+		ballotPoints[i] = system.Tally(d.Entries, []Votes{v})
+	}
+
+	place := make(map[string]int)
+	for placeIdx, p := range results.Places {
+		for _, e := range p.Entries {
+			place[e.ID] = placeIdx + 1
+			es := EntryStats{
+				Entry:        e,
+				Place:        placeIdx + 1,
+				Distribution: make([]int, len(stats.Options)),
+			}
+			for i, v := range votes {
+				if value := v.Scores[e.ID]; value != 0 {
+					es.Voters++
+					if idx := slices.IndexFunc(stats.Options, func(o VoteOption) bool { return o.Value == value }); idx >= 0 {
+						es.Distribution[idx]++
+					}
+				}
+				es.Mean += float64(ballotPoints[i][e.ID])
+			}
+			if len(votes) > 0 {
+				es.Mean /= float64(len(votes))
+				var variance float64
+				for i := range votes {
+					diff := float64(ballotPoints[i][e.ID]) - es.Mean
+					variance += diff * diff
+				}
+				es.Polarization = math.Sqrt(variance / float64(len(votes)))
+			}
+			stats.Entries = append(stats.Entries, es)
+		}
+	}
+
+	for i, es := range stats.Entries {
+		if es.Voters == 0 {
+			continue
+		}
+		if stats.MostPolarizing == nil || es.Polarization > stats.MostPolarizing.Polarization {
+			stats.MostPolarizing = &stats.Entries[i]
+		}
+	}
+
+	stats.Agreement = agreement(d.Entries, ballotPoints, place)
+	return stats
+}
+
+func agreement(entries Entries, ballotPoints []ScoreMap, place map[string]int) AgreementStats {
+	res := AgreementStats{Histogram: make([]int, agreementBuckets)}
+	var values []float64
+	for _, points := range ballotPoints {
+		var agree, total int
+		for i, a := range entries {
+			for _, b := range entries[i+1:] {
+				if points[a.ID] == points[b.ID] || place[a.ID] == place[b.ID] {
+					continue
+				}
+				total++
+				if (points[a.ID] > points[b.ID]) == (place[a.ID] < place[b.ID]) {
+					agree++
+				}
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		value := float64(agree) / float64(total)
+		values = append(values, value)
+		res.Histogram[min(int(value*agreementBuckets), agreementBuckets-1)]++
+	}
+	res.Voters = len(values)
+	if len(values) == 0 {
+		return res
+	}
+	slices.Sort(values)
+	for _, v := range values {
+		res.Mean += v
+	}
+	res.Mean /= float64(len(values))
+	if n := len(values); n%2 == 1 {
+		res.Median = values[n/2]
+	} else {
+		res.Median = (values[n/2-1] + values[n/2]) / 2
+	}
+	return res
+}
+
+// turnout groups ballots by when they were started and last changed. The
+// period is an hour for votes cast within two days, a day for votes cast
+// within three months and a week otherwise.
+func turnout(votes []Votes) []TurnoutPoint {
+	if len(votes) == 0 {
+		return nil
+	}
+	updatedAt := func(v Votes) time.Time {
+		if v.UpdatedAt.Before(v.CreatedAt) {
+			return v.CreatedAt
+		}
+		return v.UpdatedAt
+	}
+	first, last := votes[0].CreatedAt, updatedAt(votes[0])
+	for _, v := range votes {
+		if v.CreatedAt.Before(first) {
+			first = v.CreatedAt
+		}
+		if updatedAt(v).After(last) {
+			last = updatedAt(v)
+		}
+	}
+	period := time.Hour
+	switch span := last.Sub(first); {
+	case span > 90*24*time.Hour:
+		period = 7 * 24 * time.Hour
+	case span > 48*time.Hour:
+		period = 24 * time.Hour
+	}
+
+	start := first.UTC().Truncate(period)
+	var points []TurnoutPoint
+	for t := start; !t.After(last); t = t.Add(period) {
+		points = append(points, TurnoutPoint{Start: t})
+	}
+	index := func(t time.Time) int {
+		return min(max(int(t.Sub(start)/period), 0), len(points)-1)
+	}
+	for _, v := range votes {
+		points[index(v.CreatedAt)].New++
+		points[index(updatedAt(v))].Updated++
+	}
+	var total int
+	for i := range points {
+		total += points[i].New
+		points[i].Total = total
+	}
+	return points
+}
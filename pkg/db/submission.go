@@ -0,0 +1,97 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	SubmissionClosed = errors.New("battle is not accepting entries")
+	DuplicateEntry   = errors.New("duplicate entry")
+)
+
+// AcceptsSubmissions reports whether entries can be submitted at now.
+// Submissions end when voting is scheduled to open even if the scheduler has
+// not moved the battle yet.
+func (d Battle) AcceptsSubmissions(now time.Time) bool {
+	return d.Phase == PhaseSubmission && (d.OpensAt.IsZero() || now.Before(d.OpensAt))
+}
+
+// GetEntryByUploader returns the entry uploaded by uploaderID.
+func (d *Battle) GetEntryByUploader(uploaderID string) (Entry, bool) {
+	if uploaderID == "" {
+		return Entry{}, false
+	}
+	for _, e := range d.Entries {
+		if uploaderID == e.UploaderID {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// SubmitEntry adds an uploaded entry to a battle, or replaces the entry
+// previously uploaded by the same uploader. Each author and uploader can have
+// one entry in a battle. storeFile is called with the entry before it is
+// committed, nothing is stored if it fails. The stored entry and the
+// replaced entry, if any, are returned.
+func (db *DB) SubmitEntry(battleName string, entry Entry, storeFile func(entry Entry) error) (Entry, *Entry, error) {
+	var replaced *Entry
+	err := db.BoltDB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(battlesBucketName))
+		if bucket == nil {
+			return NotFound
+		}
+		battle, err := getBattle(bucket, battleName)
+		if err != nil {
+			return err
+		}
+		if battle == nil {
+			return NotFound
+		}
+		now := time.Now()
+		if !battle.AcceptsSubmissions(now) {
+			return SubmissionClosed
+		}
+
+		idx := -1
+		for i, e := range battle.Entries {
+			if entry.UploaderID != "" && e.UploaderID == entry.UploaderID {
+				idx = i
+				continue
+			}
+			if strings.EqualFold(strings.TrimSpace(e.Author), strings.TrimSpace(entry.Author)) {
+				return fmt.Errorf("%w: %s already has an entry", DuplicateEntry, e.Author)
+			}
+			if e.Filename == entry.Filename {
+				return fmt.Errorf("%w: file %s exists", DuplicateEntry, e.Filename)
+			}
+		}
+
+		entry.SubmittedAt = now
+		if idx >= 0 {
+			prev := battle.Entries[idx]
+			replaced = &prev
+			entry.ID = prev.ID
+			entry.CreatedAt = prev.CreatedAt
+			battle.Entries[idx] = entry
+		} else {
+			entry.ID = xid.New().String()
+			entry.CreatedAt = now
+			battle.Entries = append(battle.Entries, entry)
+		}
+		if err := putBattle(bucket, *battle); err != nil {
+			return err
+		}
+		return storeFile(entry)
+	})
+	if err != nil {
+		return Entry{}, nil, err
+	}
+	return entry, replaced, nil
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSubmitEntryStoreFileFails(t *testing.T) {
+	db := newTestDB(t)
+	newTestBattle(t, db, "b", "a.wav")
+	if err := db.SetPhase("b", PhaseSubmission, "test"); err != nil {
+		t.Fatal(err)
+	}
+	errRename := errors.New("rename failed")
+	entry := Entry{Author: "Al", Title: "Song", Filename: "Al - Song.wav", UploaderID: "cookie:al"}
+	_, _, err := db.SubmitEntry("b", entry, func(Entry) error { return errRename })
+	if !errors.Is(err, errRename) {
+		t.Fatalf("err = %v, want %v", err, errRename)
+	}
+	battle, err := db.GetBattle("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(battle.Entries) != 1 {
+		t.Errorf("entries = %+v, want the failed upload left out", battle.Entries)
+	}
+
+	var stored Entry
+	entry, _, err = db.SubmitEntry("b", entry, func(e Entry) error {
+		stored = e
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ID == "" || stored.ID != entry.ID {
+		t.Errorf("stored entry %q, submitted %q", stored.ID, entry.ID)
+	}
+}
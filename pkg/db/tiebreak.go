@@ -0,0 +1,92 @@
+package db
+
+import (
+	"cmp"
+	"slices"
+)
+
+// TieBreakRule orders entries with equal scores.
+type TieBreakRule string
+
+const (
+	// TieBreakFirstPlaceVotes prefers the entry which was given the best
+	// possible value on more ballots.
+	TieBreakFirstPlaceVotes TieBreakRule = "first_place_votes"
+	// TieBreakVotesReceived prefers the entry which is on more ballots.
+	TieBreakVotesReceived TieBreakRule = "votes_received"
+	// TieBreakEarliestSubmission prefers the entry which was submitted first.
+	TieBreakEarliestSubmission TieBreakRule = "earliest_submission"
+)
+
+var DefaultTieBreakRules = []TieBreakRule{
+	TieBreakFirstPlaceVotes,
+	TieBreakVotesReceived,
+	TieBreakEarliestSubmission,
+}
+
+func (r TieBreakRule) Valid() bool {
+	return slices.Contains(DefaultTieBreakRules, r)
+}
+
+// Description returns a human readable description of the rule.
+func (r TieBreakRule) Description() string {
+	switch r {
+	case TieBreakFirstPlaceVotes:
+		return "most first place votes"
+	case TieBreakVotesReceived:
+		return "most votes received"
+	case TieBreakEarliestSubmission:
+		return "earliest submission"
+	}
+	return string(r)
+}
+
+// Compare returns a negative number if a should be placed before b.
+func (r TieBreakRule) Compare(a, b Entry, stats TieBreakStats) int {
+	switch r {
+	case TieBreakFirstPlaceVotes:
+		return cmp.Compare(stats.FirstPlaceVotes[b.ID], stats.FirstPlaceVotes[a.ID])
+	case TieBreakVotesReceived:
+		return cmp.Compare(stats.VotesReceived[b.ID], stats.VotesReceived[a.ID])
+	case TieBreakEarliestSubmission:
+		return a.SubmissionTime().Compare(b.SubmissionTime())
+	}
+	return 0
+}
+
+// decidingRule returns the first rule which separates a and b or an empty
+// rule if they are tied by all rules.
+func decidingRule(rules []TieBreakRule, a, b Entry, stats TieBreakStats) TieBreakRule {
+	for _, rule := range rules {
+		if rule.Compare(a, b, stats) != 0 {
+			return rule
+		}
+	}
+	return ""
+}
+
+// TieBreakStats holds the per entry counts used by tie-break rules.
+type TieBreakStats struct {
+	// FirstPlaceVotes is the number of ballots giving an entry the best
+	// possible value.
+	FirstPlaceVotes map[string]int
+	// VotesReceived is the number of ballots an entry is on.
+	VotesReceived map[string]int
+}
+
+func NewTieBreakStats(system ScoringSystem, numEntries int, votes []Votes) TieBreakStats {
+	stats := TieBreakStats{
+		FirstPlaceVotes: make(map[string]int),
+		VotesReceived:   make(map[string]int),
+	}
+	first := system.FirstPlace(numEntries)
+	for _, v := range votes {
+		for id, score := range v.Scores {
+			stats.VotesReceived[id]++
+			if score == first {
+				stats.FirstPlaceVotes[id]++
+			}
+		}
+	}
+	return stats
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// placesString formats places as "A B/decided_by C" with entries sharing a
+// place separated by commas, the rule is left out when no rule decided.
+func placesString(places Places) string {
+	var res []string
+	for _, place := range places {
+		var ids []string
+		for _, e := range place.Entries {
+			ids = append(ids, e.ID)
+		}
+		s := strings.Join(ids, ",")
+		if place.DecidedBy != "" {
+			s += "/" + string(place.DecidedBy)
+		}
+		res = append(res, s)
+	}
+	return strings.Join(res, " ")
+}
+
+func TestPlacesTieBreak(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	entries := func(submitted ...int) Entries {
+		var res Entries
+		for i, hours := range submitted {
+			id := string(rune('A' + i))
+			res = append(res, Entry{
+				ID:          id,
+				Author:      id,
+				SubmittedAt: start.Add(time.Duration(hours) * time.Hour),
+			})
+		}
+		return res
+	}
+
+	tests := []struct {
+		name    string
+		entries Entries
+		scores  ScoreMap
+		stats   TieBreakStats
+		rules   []TieBreakRule
+		want    string
+	}{
+		{
+			name:    "no tie",
+			entries: entries(0, 0, 0),
+			scores:  ScoreMap{"A": 1, "B": 3, "C": 2},
+			rules:   DefaultTieBreakRules,
+			want:    "B C A",
+		},
+		{
+			name:    "first place votes",
+			entries: entries(0, 0, 0),
+			scores:  ScoreMap{"A": 3, "B": 3, "C": 1},
+			stats: TieBreakStats{
+				FirstPlaceVotes: map[string]int{"B": 1},
+				VotesReceived:   map[string]int{"A": 3, "B": 1},
+			},
+			rules: DefaultTieBreakRules,
+			want:  "B/first_place_votes A/first_place_votes C",
+		},
+		{
+			name:    "votes received",
+			entries: entries(0, 0, 0),
+			scores:  ScoreMap{"A": 3, "B": 3, "C": 1},
+			stats: TieBreakStats{
+				FirstPlaceVotes: map[string]int{"A": 1, "B": 1},
+				VotesReceived:   map[string]int{"A": 1, "B": 2},
+			},
+			rules: DefaultTieBreakRules,
+			want:  "B/votes_received A/votes_received C",
+		},
+		{
+			name:    "earliest submission",
+			entries: entries(2, 1, 0),
+			scores:  ScoreMap{"A": 3, "B": 3, "C": 1},
+			rules:   DefaultTieBreakRules,
+			want:    "B/earliest_submission A/earliest_submission C",
+		},
+		{
+			name:    "earliest submission falls back to the creation time",
+			entries: Entries{{ID: "A", CreatedAt: start.Add(time.Hour)}, {ID: "B", CreatedAt: start}},
+			scores:  ScoreMap{"A": 1, "B": 1},
+			rules:   DefaultTieBreakRules,
+			want:    "B/earliest_submission A/earliest_submission",
+		},
+		{
+			name:    "rules apply in the configured order",
+			entries: entries(0, 1, 2),
+			scores:  ScoreMap{"A": 3, "B": 3, "C": 1},
+			stats: TieBreakStats{
+				VotesReceived: map[string]int{"A": 1, "B": 2},
+			},
+			rules: []TieBreakRule{TieBreakEarliestSubmission, TieBreakVotesReceived},
+			want:  "A/earliest_submission B/earliest_submission C",
+		},
+		{
+			name:    "later rule decides when earlier rules tie",
+			entries: entries(0, 1, 2),
+			scores:  ScoreMap{"A": 3, "B": 3, "C": 1},
+			stats: TieBreakStats{
+				FirstPlaceVotes: map[string]int{"A": 1, "B": 1},
+				VotesReceived:   map[string]int{"A": 2, "B": 2},
+			},
+			rules: DefaultTieBreakRules,
+			want:  "A/earliest_submission B/earliest_submission C",
+		},
+		{
+			name:    "shared place without rules",
+			entries: entries(0, 1, 2),
+			scores:  ScoreMap{"A": 3, "B": 3, "C": 1},
+			stats: TieBreakStats{
+				FirstPlaceVotes: map[string]int{"B": 1},
+			},
+			want: "A,B C",
+		},
+		{
+			name:    "shared place when all rules tie",
+			entries: entries(0, 0, 2),
+			scores:  ScoreMap{"A": 2, "B": 2, "C": 2},
+			rules:   DefaultTieBreakRules,
+			want:    "A,B/earliest_submission C/earliest_submission",
+		},
+		{
+			name:    "three way tie decided by two rules",
+			entries: entries(1, 0, 0),
+			scores:  ScoreMap{"A": 2, "B": 2, "C": 2},
+			stats: TieBreakStats{
+				FirstPlaceVotes: map[string]int{"A": 2, "B": 1, "C": 1},
+			},
+			rules: DefaultTieBreakRules,
+			want:  "A/first_place_votes B,C/first_place_votes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			places := tt.entries.Places(tt.scores, tt.rules, tt.stats)
+			if got := placesString(places); got != tt.want {
+				t.Errorf("places = %q, want %q", got, tt.want)
+			}
+			for _, place := range places {
+				if place.Shared() != (len(place.Entries) > 1) {
+					t.Errorf("place %v: shared = %v", place.Entries, place.Shared())
+				}
+			}
+		})
+	}
+}
+
+func TestNewTieBreakStats(t *testing.T) {
+	system, _ := NewScoringSystem(ScoringConfig{})
+	votes := []Votes{
+		{Scores: ScoreMap{"A": 3, "B": 2, "C": 1}},
+		{Scores: ScoreMap{"B": 3, "A": 1}},
+		{Scores: ScoreMap{"B": 3}},
+	}
+	stats := NewTieBreakStats(system, 3, votes)
+	got := fmt.Sprint(stats.FirstPlaceVotes, stats.VotesReceived)
+	if want := "map[A:1 B:2] map[A:2 B:3 C:1]"; got != want {
+		t.Errorf("stats = %s, want %s", got, want)
+	}
+}
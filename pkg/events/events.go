@@ -0,0 +1,94 @@
+// Package events is an in-process publish/subscribe bus for battle activity.
+package events
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// Type is the kind of an event.
+type Type string
+
+const (
+	// VoterCount is sent when the number of voters of a battle changes.
+	VoterCount Type = "voter_count"
+	// BattleOpened is sent when voting opens.
+	BattleOpened Type = "battle_opened"
+	// BattleClosed is sent when voting closes.
+	BattleClosed Type = "battle_closed"
+	// ResultsPublished is sent when the results of a battle are published.
+	ResultsPublished Type = "results_published"
+	// Revealed is sent when the reveal of the results changes.
+	Revealed Type = "revealed"
+)
+
+// Event is sent to subscribers as JSON.
+type Event struct {
+	Type   Type   `json:"type"`
+	Battle string `json:"battle"`
+	// Voters is set for VoterCount events.
+	Voters int `json:"voters"`
+	// Revealed is the number of revealed places for Revealed events.
+	Revealed int `json:"revealed"`
+}
+
+// subscriberBuffer is the number of events buffered for each subscriber,
+// events are dropped for subscribers which fall further behind.
+const subscriberBuffer = 16
+
+// Bus delivers published events to the subscribers of the battle. A nil *Bus
+// discards all events.
+type Bus struct {
+	mu   sync.Mutex
+	subs map[string]map[chan Event]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving the events of a battle. The returned
+// function must be called to unsubscribe, it closes the channel.
+func (b *Bus) Subscribe(battleName string) (<-chan Event, func()) {
+	if b == nil {
+		return make(chan Event), func() {}
+	}
+	ch := make(chan Event, subscriberBuffer)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[battleName] == nil {
+		b.subs[battleName] = make(map[chan Event]struct{})
+	}
+	b.subs[battleName][ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs[battleName], ch)
+			if len(b.subs[battleName]) == 0 {
+				delete(b.subs, battleName)
+			}
+			close(ch)
+		})
+	}
+}
+
+// Publish sends an event to all subscribers of its battle without blocking.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[event.Battle] {
+		select {
+		case ch <- event:
+		default:
+			slog.Warn("dropping event for slow subscriber", "event", event)
+		}
+	}
+}
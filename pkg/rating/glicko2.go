@@ -0,0 +1,199 @@
+// Package rating implements the Glicko-2 rating system.
+//
+// See http://www.glicko.net/glicko/glicko2.pdf for the description of the
+// algorithm.
+package rating
+
+import (
+	"math"
+	"slices"
+)
+
+const (
+	// glickoScale converts between the Glicko and the Glicko-2 scale.
+	glickoScale = 173.7178
+	// convergence is the tolerance of the volatility iteration.
+	convergence = 0.000001
+)
+
+// DefaultTau constrains the change in volatility over time.
+const DefaultTau = 0.5
+
+// Rating is a Glicko rating, Deviation is the rating deviation (RD).
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+}
+
+// Initial is the rating of an unrated player.
+var Initial = Rating{Rating: 1500, Deviation: 350, Volatility: 0.06}
+
+// Result is the outcome of a game against an opponent. Score is 1 for a win,
+// 0.5 for a draw and 0 for a loss.
+type Result struct {
+	Opponent Rating
+	Score    float64
+}
+
+// Update returns the rating of a player after a rating period with the given
+// results. A player without results only has their deviation increased.
+func Update(r Rating, results []Result, tau float64) Rating {
+	mu := (r.Rating - 1500) / glickoScale
+	phi := r.Deviation / glickoScale
+	sigma := r.Volatility
+
+	if len(results) == 0 {
+		return Rating{
+			Rating:     r.Rating,
+			Deviation:  math.Min(math.Sqrt(phi*phi+sigma*sigma)*glickoScale, Initial.Deviation),
+			Volatility: sigma,
+		}
+	}
+
+	var vInv, deltaSum float64
+	for _, res := range results {
+		muJ := (res.Opponent.Rating - 1500) / glickoScale
+		phiJ := res.Opponent.Deviation / glickoScale
+		g := g(phiJ)
+		e := expected(mu, muJ, g)
+		vInv += g * g * e * (1 - e)
+		deltaSum += g * (res.Score - e)
+	}
+	v := 1 / vInv
+	delta := v * deltaSum
+
+	sigma = volatility(phi, sigma, v, delta, tau)
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu = mu + phi*phi*deltaSum
+
+	return Rating{
+		Rating:     mu*glickoScale + 1500,
+		Deviation:  phi * glickoScale,
+		Volatility: sigma,
+	}
+}
+
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func expected(mu, muJ, g float64) float64 {
+	return 1 / (1 + math.Exp(-g*(mu-muJ)))
+}
+
+// volatility computes the new volatility with the Illinois algorithm.
+func volatility(phi, sigma, v, delta, tau float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > convergence {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA = fA / 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
+
+// Period is a rating period where every player plays every other player.
+// Groups are the players ordered from best to worst, players beat the
+// players of later groups and draw with the players of the same group.
+type Period struct {
+	ID     string
+	Groups [][]string
+}
+
+// Point is the rating of a player after a period.
+type Point struct {
+	Period string `json:"period"`
+	Rating
+}
+
+// History is the rating history of each player, with a point for each period
+// the player was in.
+type History map[string][]Point
+
+// Current returns the latest rating of a player.
+func (h History) Current(player string) (Rating, bool) {
+	points := h[player]
+	if len(points) == 0 {
+		return Rating{}, false
+	}
+	return points[len(points)-1].Rating, true
+}
+
+// Compute rates the periods in order. The result only depends on the order of
+// the periods and the groups, not on the order within a group.
+func Compute(periods []Period, tau float64) History {
+	ratings := make(map[string]Rating)
+	history := make(History)
+	for _, period := range periods {
+		place := make(map[string]int)
+		var players []string
+		for i, group := range period.Groups {
+			for _, p := range group {
+				if _, ok := place[p]; ok {
+					continue
+				}
+				place[p] = i
+				players = append(players, p)
+			}
+		}
+		slices.Sort(players)
+
+		rating := func(p string) Rating {
+			if r, ok := ratings[p]; ok {
+				return r
+			}
+			return Initial
+		}
+
+		updated := make(map[string]Rating, len(ratings)+len(players))
+		for _, p := range players {
+			var results []Result
+			for _, o := range players {
+				if o == p {
+					continue
+				}
+				score := 0.5
+				if place[p] < place[o] {
+					score = 1
+				} else if place[p] > place[o] {
+					score = 0
+				}
+				results = append(results, Result{Opponent: rating(o), Score: score})
+			}
+			updated[p] = Update(rating(p), results, tau)
+			history[p] = append(history[p], Point{Period: period.ID, Rating: updated[p]})
+		}
+		for p, r := range ratings {
+			if _, ok := place[p]; !ok {
+				updated[p] = Update(r, nil, tau)
+			}
+		}
+		ratings = updated
+	}
+	return history
+}
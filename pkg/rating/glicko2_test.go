@@ -0,0 +1,83 @@
+package rating
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestUpdateGlickmanExample(t *testing.T) {
+	// the worked example of section 3 of Glickman's "Example of the
+	// Glicko-2 system".
+	player := Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	results := []Result{
+		{Opponent: Rating{Rating: 1400, Deviation: 30}, Score: 1},
+		{Opponent: Rating{Rating: 1550, Deviation: 100}, Score: 0},
+		{Opponent: Rating{Rating: 1700, Deviation: 300}, Score: 0},
+	}
+	got := Update(player, results, 0.5)
+	tests := []struct {
+		name      string
+		got, want float64
+		tolerance float64
+	}{
+		{"rating", got.Rating, 1464.06, 0.01},
+		{"deviation", got.Deviation, 151.52, 0.01},
+		{"volatility", got.Volatility, 0.05999, 0.00001},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > tt.tolerance {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestUpdateWithoutResults(t *testing.T) {
+	player := Rating{Rating: 1600, Deviation: 200, Volatility: 0.06}
+	got := Update(player, nil, DefaultTau)
+	if got.Rating != player.Rating || got.Volatility != player.Volatility {
+		t.Errorf("rating changed to %+v", got)
+	}
+	want := math.Sqrt(200*200 + math.Pow(0.06*glickoScale, 2))
+	if math.Abs(got.Deviation-want) > 1e-9 {
+		t.Errorf("deviation = %v, want %v", got.Deviation, want)
+	}
+	if got := Update(Initial, nil, DefaultTau); got.Deviation != Initial.Deviation {
+		t.Errorf("deviation = %v, want it capped at %v", got.Deviation, Initial.Deviation)
+	}
+}
+
+func TestComputeDeterministic(t *testing.T) {
+	periods := []Period{
+		{ID: "b1", Groups: [][]string{{"al"}, {"bo", "cy"}, {"di"}}},
+		{ID: "b2", Groups: [][]string{{"cy"}, {"al"}, {"ed"}}},
+		{ID: "b3", Groups: [][]string{{"di", "ed"}, {"bo"}}},
+	}
+	// the same periods with the players of each group in another order.
+	reordered := []Period{
+		{ID: "b1", Groups: [][]string{{"al"}, {"cy", "bo"}, {"di"}}},
+		{ID: "b2", Groups: [][]string{{"cy"}, {"al"}, {"ed"}}},
+		{ID: "b3", Groups: [][]string{{"ed", "di"}, {"bo"}}},
+	}
+	first := Compute(periods, DefaultTau)
+	if again := Compute(periods, DefaultTau); !reflect.DeepEqual(first, again) {
+		t.Errorf("recomputing gave %v, want %v", again, first)
+	}
+	if other := Compute(reordered, DefaultTau); !reflect.DeepEqual(first, other) {
+		t.Errorf("reordered groups gave %v, want %v", other, first)
+	}
+
+	for player, points := range map[string]int{"al": 2, "bo": 2, "cy": 2, "di": 2, "ed": 2} {
+		if got := len(first[player]); got != points {
+			t.Errorf("%s has %d points, want %d", player, got, points)
+		}
+	}
+	al, _ := first.Current("al")
+	bo, _ := first.Current("bo")
+	if al.Rating <= bo.Rating {
+		t.Errorf("al %v is not rated above bo %v", al.Rating, bo.Rating)
+	}
+	if _, ok := first.Current("nobody"); ok {
+		t.Error("unknown player has a rating")
+	}
+}
@@ -1,40 +1,138 @@
 package scanner
 
 import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"io/fs"
+	"log/slog"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
+
+	"github.com/some-programs/battlr/pkg/audiotag"
+	"gopkg.in/yaml.v3"
+)
+
+// MetaFilename is the name of the optional metadata sidecar file in a battle
+// directory.
+const MetaFilename = "battle.yaml"
+
+// Sources for entry author and title information.
+const (
+	SourceTags     = "tags"
+	SourceFilename = "filename"
 )
 
+// EntryExtensions are the file extensions of audio files which are read as
+// battle entries.
+var EntryExtensions = []string{".wav", ".mp3", ".ogg", ".flac"}
+
+// IsEntryFile reports whether filename has one of EntryExtensions.
+func IsEntryFile(filename string) bool {
+	return slices.Contains(EntryExtensions, strings.ToLower(filepath.Ext(filename)))
+}
+
 type Entry struct {
 	Author   string
 	Title    string
 	Filename string
 	Path     string
+	// Hash is the hex encoded sha256 sum of the file contents.
+	Hash    string
+	ModTime time.Time
 }
 
 type Battle struct {
 	Name    string
 	Entries []Entry
+	Meta    Meta
+}
+
+// Meta is the contents of a battle metadata sidecar file.
+type Meta struct {
+	// Title is the display name of the battle, the directory name is used if
+	// it is empty.
+	Title string `yaml:"title"`
+	// Description is Markdown text shown on the vote and results pages.
+	Description string    `yaml:"description"`
+	Theme       string    `yaml:"theme"`
+	OpensAt     time.Time `yaml:"opens_at"`
+	ClosesAt    time.Time `yaml:"closes_at"`
+	// Prefer selects which of SourceTags (the default) or SourceFilename has
+	// priority when both contain an author or title.
+	Prefer  string      `yaml:"prefer"`
+	Scoring ScoringMeta `yaml:"scoring"`
+	// TieBreak lists the tie-break rules in the order they are applied.
+	TieBreak []string `yaml:"tie_break"`
+	// Comments is private or public, see db.CommentVisibility.
+	Comments string `yaml:"comments"`
+	// InviteOnly only allows voting with invite tokens.
+	InviteOnly bool `yaml:"invite_only"`
+	// Participation requires entrants to vote, see db.ParticipationConfig.
+	Participation ParticipationMeta `yaml:"participation"`
+	// Roles maps voter roles to their weight, see db.RoleWeights.
+	Roles map[string]int `yaml:"roles"`
+	// Entries holds per entry overrides keyed by filename.
+	Entries map[string]EntryMeta `yaml:"entries"`
+}
+
+// ScoringMeta selects the scoring system of a battle, see db.ScoringConfig.
+type ScoringMeta struct {
+	System string `yaml:"system"`
+	Points []int  `yaml:"points"`
+}
+
+// ParticipationMeta is the participation rule of a battle.
+type ParticipationMeta struct {
+	Rule    string `yaml:"rule"`
+	Penalty int    `yaml:"penalty"`
+}
+
+// EntryMeta overrides the values guessed from an entry filename.
+type EntryMeta struct {
+	Author string `yaml:"author"`
+	Title  string `yaml:"title"`
+}
+
+// BattleError is a battle which could not be read.
+type BattleError struct {
+	Name string
+	Err  error
+}
+
+func (e BattleError) Error() string {
+	return fmt.Sprintf("battle %s: %v", e.Name, e.Err)
+}
+
+func (e BattleError) Unwrap() error {
+	return e.Err
 }
 
+// GetAllBattles reads every battle. A battle which cannot be read is left out
+// and reported in errs, err is only returned if the names cannot be read.
 func GetAllBattles(
 	getNamesFunc func() ([]string, error),
 	getBattleFunc func(name string) (Battle, error),
-) ([]Battle, error) {
+) (battles []Battle, errs []BattleError, err error) {
 	names, err := getNamesFunc()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	var battles []Battle
 	for _, name := range names {
 		battle, err := getBattleFunc(name)
 		if err != nil {
-			return nil, err
+			errs = append(errs, BattleError{Name: name, Err: err})
+			continue
 		}
 		battles = append(battles, battle)
 	}
-	return battles, nil
+	return battles, errs, nil
 }
 
 // FSScanner .
@@ -62,6 +160,12 @@ func (s *FSScanner) GetBattle(name string) (Battle, error) {
 		Name: name,
 	}
 
+	meta, err := s.GetMeta(name)
+	if err != nil {
+		return battle, err
+	}
+	battle.Meta = meta
+
 	entries, err := fs.ReadDir(s.Fsys, name)
 	if err != nil {
 		return battle, err
@@ -72,34 +176,110 @@ func (s *FSScanner) GetBattle(name string) (Battle, error) {
 			continue
 		}
 		filename := entry.Name()
-		ext := filepath.Ext(filename)
-		switch strings.ToLower(ext) {
-		case ".wav", ".mp3", ".ogg", ".flac":
-			// do nothing
-		default:
+		if !IsEntryFile(filename) {
 			continue
 		}
+		ext := filepath.Ext(filename)
 
-		author, title, ok := strings.Cut(strings.TrimSuffix(filename, ext), "-")
-		if !ok {
-			author, title = title, author
+		fullPath := filepath.Join(name, filename)
+		info, err := entry.Info()
+		if err != nil {
+			return battle, err
 		}
 
-		author = strings.TrimSpace(replaceSpaces.Replace(author))
-		title = strings.TrimSpace(replaceSpaces.Replace(title))
+		author, title := splitFilename(strings.TrimSuffix(filename, ext))
+		tags, hash, err := s.readFile(fullPath, ext)
+		if err != nil {
+			return battle, err
+		}
+		if meta.Prefer == SourceFilename {
+			author = cmp.Or(author, tags.Artist)
+			title = cmp.Or(title, tags.Title)
+		} else {
+			author = cmp.Or(tags.Artist, author)
+			title = cmp.Or(tags.Title, title)
+		}
+
+		if em, ok := meta.Entries[filename]; ok {
+			if em.Author != "" {
+				author = em.Author
+			}
+			if em.Title != "" {
+				title = em.Title
+			}
+		}
 
-		fullPath := filepath.Join(name, filename)
 		battle.Entries = append(battle.Entries, Entry{
 			Author:   author,
 			Title:    title,
 			Filename: filename,
 			Path:     fullPath,
+			Hash:     hash,
+			ModTime:  info.ModTime(),
 		})
 	}
 	return battle, nil
 }
 
-var replaceSpaces = strings.NewReplacer(generateReplacerPairs("-_", " ")...)
+// GetMeta reads the metadata sidecar file for a battle. A missing file is not
+// an error.
+func (s *FSScanner) GetMeta(name string) (Meta, error) {
+	var meta Meta
+	data, err := fs.ReadFile(s.Fsys, path.Join(name, MetaFilename))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return meta, nil
+		}
+		return meta, err
+	}
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return meta, fmt.Errorf("%s: %w", path.Join(name, MetaFilename), err)
+	}
+	return meta, nil
+}
+
+// readFile reads the tags and computes the content hash of an entry file in a
+// single pass. Unreadable tags are logged and otherwise ignored.
+func (s *FSScanner) readFile(name, ext string) (audiotag.Tags, string, error) {
+	f, err := s.Fsys.Open(name)
+	if err != nil {
+		return audiotag.Tags{}, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	tags, err := audiotag.Read(io.TeeReader(f, h), ext)
+	if err != nil && !errors.Is(err, audiotag.NotFound) {
+		slog.Warn("could not read tags", "path", name, "err", err)
+	}
+	if _, err := io.Copy(h, f); err != nil {
+		return tags, "", err
+	}
+	return tags, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// splitFilename guesses author and title from a filename without extension.
+// " - " is preferred as separator so that names like "DJ A-Z - track" are
+// split correctly.
+func splitFilename(name string) (author, title string) {
+	replacer := replaceUnderscores
+	author, title, ok := strings.Cut(name, " - ")
+	if !ok {
+		replacer = replaceSpaces
+		author, title, ok = strings.Cut(name, "-")
+	}
+	if !ok {
+		author, title = title, author
+	}
+	author = strings.TrimSpace(replacer.Replace(author))
+	title = strings.TrimSpace(replacer.Replace(title))
+	return author, title
+}
+
+var (
+	replaceSpaces      = strings.NewReplacer(generateReplacerPairs("-_", " ")...)
+	replaceUnderscores = strings.NewReplacer(generateReplacerPairs("_", " ")...)
+)
 
 func generateReplacerPairs(s, replacement string) []string {
 	pairs := make([]string, 2*len(s))
@@ -0,0 +1,26 @@
+package scanner
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestGetAllBattlesSkipsBrokenBattle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"good/Artist - Song.wav": {Data: []byte("not really a wav file")},
+		"good/battle.yaml":       {Data: []byte("title: Good\n")},
+		"bad/Artist - Song.wav":  {Data: []byte("not really a wav file")},
+		"bad/battle.yaml":        {Data: []byte("title: [unterminated\n")},
+	}
+	fsc := FSScanner{Fsys: fsys}
+	battles, errs, err := GetAllBattles(fsc.GetBattleNames, fsc.GetBattle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(battles) != 1 || battles[0].Name != "good" || battles[0].Meta.Title != "Good" {
+		t.Errorf("battles = %+v, want only good", battles)
+	}
+	if len(errs) != 1 || errs[0].Name != "bad" {
+		t.Errorf("errs = %v, want only bad", errs)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/some-programs/battlr/assets"
+	"github.com/some-programs/battlr/pkg/db"
+)
+
+// resultsTopPlaces is the number of places listed on the results page, they
+// are the places stepped through in a reveal.
+const resultsTopPlaces = 3
+
+// publicPlaces returns the number of places published on the artist and
+// season pages, 0 publishes all places.
+func (s *Server) publicPlaces() int {
+	if s.FullResultsOrder {
+		return 0
+	}
+	return resultsTopPlaces
+}
+
+// RevealResponse is the reveal state of a battle with the places to reveal.
+type RevealResponse struct {
+	Reveal db.Reveal     `json:"reveal"`
+	Hidden int           `json:"hidden"`
+	Places []RevealPlace `json:"places"`
+}
+
+type RevealPlace struct {
+	Place   int           `json:"place"`
+	Score   int           `json:"score"`
+	Entries []RevealEntry `json:"entries"`
+}
+
+type RevealEntry struct {
+	ID     string `json:"id"`
+	Author string `json:"author"`
+	Title  string `json:"title"`
+}
+
+func newRevealResponse(reveal db.Reveal, places db.Places) RevealResponse {
+	res := RevealResponse{
+		Reveal: reveal,
+		Hidden: reveal.Hidden(len(places)),
+		Places: []RevealPlace{},
+	}
+	for i, place := range places {
+		rp := RevealPlace{
+			Place: i + 1,
+			Score: place.Score,
+		}
+		for _, e := range place.Entries {
+			rp.Entries = append(rp.Entries, RevealEntry{ID: e.ID, Author: e.Author, Title: e.Title})
+		}
+		res.Places = append(res.Places, rp)
+	}
+	return res
+}
+
+// topPlaces returns the places listed on the results page.
+func (s *Server) topPlaces(battle *db.Battle) (db.Places, error) {
+	allVotes, err := s.DB.GetAllVotes(battle.Name)
+	if err != nil {
+		return nil, err
+	}
+	return battle.Results(allVotes).Places.Top(resultsTopPlaces, battle.ScoringSystem()), nil
+}
+
+func (s *Server) GetReveal() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		battle, err := s.DB.GetBattle(r.PathValue("name"))
+		if err != nil {
+			return err
+		}
+		if battle == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		places, err := s.topPlaces(battle)
+		if err != nil {
+			return err
+		}
+		WriteJSONResponse(r.Context(), w, http.StatusOK, newRevealResponse(battle.Reveal, places))
+		return nil
+	}
+}
+
+func (s *Server) UpdateReveal() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		battle, err := s.DB.GetBattle(r.PathValue("name"))
+		if err != nil {
+			return err
+		}
+		if battle == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		places, err := s.topPlaces(battle)
+		if err != nil {
+			return err
+		}
+		reveal, err := s.DB.UpdateReveal(battle.Name, db.RevealAction(r.PathValue("action")), len(places))
+		switch {
+		case errors.Is(err, db.NotFound):
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		case errors.Is(err, db.RevealNotAllowed):
+			WriteJSONResponse(r.Context(), w, http.StatusConflict, inspectError(err))
+			return nil
+		case err != nil:
+			return err
+		}
+		WriteJSONResponse(r.Context(), w, http.StatusOK, newRevealResponse(reveal, places))
+		return nil
+	}
+}
+
+// RevealPresenter is the page for stepping through a reveal. It contains no
+// results, they are loaded with the api key entered on the page.
+func (s *Server) RevealPresenter() AppHandler {
+	tmpl, err := template.New("base.html").
+		Funcs(template.FuncMap{
+			"static": assets.StaticHashFS.HashName,
+		},
+		).
+		ParseFS(assets.TemplateFS, "template/base.html", "template/battle-reveal.html")
+	if err != nil {
+		slog.Error("failed to parse clients template",
+			"err", err,
+		)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		battle, err := s.DB.GetBattle(r.PathValue("name"))
+		if err != nil {
+			return err
+		}
+		if battle == nil {
+			return db.NotFound
+		}
+
+		templateData := struct {
+			Title  string
+			Battle db.Battle
+		}{
+			Title:  "Reveal",
+			Battle: *battle,
+		}
+
+		w.WriteHeader(http.StatusOK)
+
+		if err := tmpl.Execute(w, &templateData); err != nil {
+			slog.Info("error", "err", err)
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/some-programs/battlr/pkg/db"
+)
+
+// clientIP returns the address of the client. Behind a reverse proxy the
+// last address in X-Forwarded-For is the one the proxy saw, earlier ones are
+// set by the client.
+func (s *Server) clientIP(r *http.Request) string {
+	if s.TrustProxy {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			addrs := strings.Split(forwarded[len(forwarded)-1], ",")
+			return strings.TrimSpace(addrs[len(addrs)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ballotRequest returns the metadata recorded on the ballot changed by the
+// request. Listens are recorded per browser so that they count for the
+// account the browser logs in to later.
+func (s *Server) ballotRequest(r *http.Request, battleName string) (db.BallotRequest, error) {
+	ipHash, err := s.DB.HashIP(s.clientIP(r))
+	if err != nil {
+		return db.BallotRequest{}, err
+	}
+	listens, err := s.DB.GetListens(battleName, getCookieClientID(r.Context()))
+	if err != nil {
+		return db.BallotRequest{}, err
+	}
+	return db.BallotRequest{
+		IPHash:    ipHash,
+		UserAgent: r.UserAgent(),
+		Listens:   listens,
+	}, nil
+}
+
+// ReviewBallots reports groups of suspicious ballots of a battle.
+// Listen records that the player of the vote page started playing an entry.
+func (s *Server) Listen() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		battle, err := s.DB.GetBattle(r.PathValue("name"))
+		if err != nil {
+			return err
+		}
+		if battle == nil || (!s.Unrestricted && !battle.Phase.AllowsListening()) {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		entry, ok := battle.GetEntryByID(r.PathValue("entry"))
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		if err := s.DB.RecordListen(battle.Name, entry.ID, getCookieClientID(r.Context())); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+}
+
+func (s *Server) ReviewBallots() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		battle, err := s.DB.GetBattle(r.PathValue("name"))
+		if err != nil {
+			return err
+		}
+		if battle == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		review, err := s.DB.ReviewBallots(battle.Name)
+		if err != nil {
+			return err
+		}
+		WriteJSONResponse(r.Context(), w, http.StatusOK, review)
+		return nil
+	}
+}
+
+// ExcludeBallot leaves a ballot out of the tally or counts it again, the
+// action is exclude or include.
+func (s *Server) ExcludeBallot() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		var excluded bool
+		switch r.PathValue("action") {
+		case "exclude":
+			excluded = true
+		case "include":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		err := s.DB.SetBallotExcluded(r.PathValue("name"), r.PathValue("voter"), excluded)
+		if errors.Is(err, db.NotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/some-programs/battlr/pkg/db"
+)
+
+// RoleRequest gives a voter one of the roles of a weighted battle.
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+func (s *Server) PutVoterRole() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		var req RoleRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			WriteJSONResponse(r.Context(), w, http.StatusBadRequest, inspectError(err))
+			return nil
+		}
+		if req.Role == "" {
+			WriteJSONResponse(r.Context(), w, http.StatusBadRequest, inspectError(errors.New("role is required")))
+			return nil
+		}
+		return s.setVoterRole(w, r, req.Role)
+	}
+}
+
+func (s *Server) DeleteVoterRole() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		return s.setVoterRole(w, r, "")
+	}
+}
+
+func (s *Server) setVoterRole(w http.ResponseWriter, r *http.Request, role string) error {
+	err := s.DB.SetVoterRole(r.PathValue("name"), r.PathValue("voter"), role)
+	switch {
+	case errors.Is(err, db.NotFound):
+		w.WriteHeader(http.StatusNotFound)
+		return nil
+	case errors.Is(err, db.InvalidRole):
+		WriteJSONResponse(r.Context(), w, http.StatusBadRequest, inspectError(err))
+		return nil
+	}
+	return err
+}
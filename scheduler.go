@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/some-programs/battlr/pkg/db"
+)
+
+// RunScheduler moves battles to the voting and results phases at their
+// scheduled times. The schedule and the applied transitions are stored with
+// the battles so transitions missed while the server was down are applied on
+// start.
+func (s *Server) RunScheduler(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.applySchedules(time.Now()); err != nil {
+			slog.Error("scheduler", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) applySchedules(now time.Time) error {
+	battles, err := s.DB.GetAllBattles()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, b := range battles {
+		var opened bool
+		if b.ShouldOpen(now) {
+			slog.Info("scheduler: opening battle", "battle", b.Name, "opens_at", b.OpensAt)
+			if err := s.DB.OpenBattle(b.Name, db.ActorScheduler); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			opened = true
+		}
+		// A battle whose whole voting period passed while the server was
+		// down is closed right after it is opened.
+		closeMissed := opened && b.ClosesAt.After(b.OpensAt) && !now.Before(b.ClosesAt)
+		if b.ShouldClose(now) || closeMissed {
+			slog.Info("scheduler: closing battle", "battle", b.Name, "closes_at", b.ClosesAt)
+			if err := s.DB.CloseBattle(b.Name, db.ActorScheduler); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/some-programs/battlr/assets"
+	"github.com/some-programs/battlr/pkg/db"
+)
+
+func (s *Server) SeasonPage() AppHandler {
+	tmpl, err := template.New("base.html").
+		Funcs(template.FuncMap{
+			"static": assets.StaticHashFS.HashName,
+			"add": func(i, j int) int {
+				return i + j
+			},
+		},
+		).
+		ParseFS(assets.TemplateFS, "template/base.html", "template/season.html")
+	if err != nil {
+		slog.Error("failed to parse clients template",
+			"err", err,
+		)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		season, err := s.DB.GetSeason(r.PathValue("name"))
+		if err != nil {
+			return err
+		}
+		if season == nil {
+			return db.NotFound
+		}
+		results, err := s.DB.SeasonResults(*season, s.publicPlaces())
+		if err != nil {
+			return err
+		}
+
+		templateData := struct {
+			Title        string
+			Results      db.SeasonResults
+			PublicPlaces int
+		}{
+			Title:        season.DisplayName(),
+			Results:      results,
+			PublicPlaces: s.publicPlaces(),
+		}
+
+		w.WriteHeader(http.StatusOK)
+
+		if err := tmpl.Execute(w, &templateData); err != nil {
+			slog.Info("error", "err", err)
+			return err
+		}
+		return nil
+	}
+}
+
+func (s *Server) GetSeasonResults() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		season, err := s.DB.GetSeason(r.PathValue("name"))
+		if err != nil {
+			return err
+		}
+		if season == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		results, err := s.DB.SeasonResults(*season, s.publicPlaces())
+		if err != nil {
+			return err
+		}
+		WriteJSONResponse(r.Context(), w, http.StatusOK, results)
+		return nil
+	}
+}
+
+// PutSeason creates or replaces the season named in the path from a JSON
+// encoded db.Season.
+func (s *Server) PutSeason() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		var season db.Season
+		if err := json.Unmarshal(data, &season); err != nil {
+			WriteJSONResponse(r.Context(), w, http.StatusBadRequest, inspectError(err))
+			return nil
+		}
+		season.Name = r.PathValue("name")
+		if err := s.DB.PutSeason(season); err != nil {
+			if errors.Is(err, db.InvalidSeason) {
+				WriteJSONResponse(r.Context(), w, http.StatusBadRequest, inspectError(err))
+				return nil
+			}
+			return err
+		}
+		WriteJSONResponse(r.Context(), w, http.StatusOK, season)
+		return nil
+	}
+}
+
+func (s *Server) DeleteSeason() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		err := s.DB.DeleteSeason(r.PathValue("name"))
+		if errors.Is(err, db.NotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"html/template"
+	"log/slog"
+	"math"
+	"net/http"
+
+	"github.com/some-programs/battlr/assets"
+	"github.com/some-programs/battlr/pkg/db"
+)
+
+// StatsPage shows how the vote of a closed battle went. It gives the scores
+// away so it is only available when scores are shown.
+func (s *Server) StatsPage() AppHandler {
+	tmpl, err := template.New("base.html").
+		Funcs(template.FuncMap{
+			"static": assets.StaticHashFS.HashName,
+			"percent": func(f float64) int {
+				return int(math.Round(f * 100))
+			},
+			"share": func(n, total int) int {
+				if total == 0 {
+					return 0
+				}
+				return n * 100 / total
+			},
+		},
+		).
+		ParseFS(assets.TemplateFS, "template/base.html", "template/battle-stats.html")
+	if err != nil {
+		slog.Error("failed to parse clients template",
+			"err", err,
+		)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		name := r.PathValue("name")
+		battle, err := s.DB.GetBattle(name)
+		if err != nil {
+			return err
+		}
+		if battle == nil {
+			return db.NotFound
+		}
+		if !s.Unrestricted {
+			if battle.Phase == db.PhaseHidden {
+				return db.NotFound
+			}
+			if !s.ShowScores {
+				return s.ErrorPage(r.Context(), w, r, "scores are not shown", "/battles/results/"+battle.Name+"/")
+			}
+			places, err := s.topPlaces(battle)
+			if err != nil {
+				return err
+			}
+			if battle.Phase != db.PhaseResults || battle.Reveal.Hidden(len(places)) > 0 {
+				return s.ErrorPage(r.Context(), w, r, "results are not published yet", "/battles/")
+			}
+		}
+
+		votes, err := s.DB.GetAllVotes(name)
+		if err != nil {
+			return err
+		}
+
+		templateData := struct {
+			Title  string
+			Battle db.Battle
+			Stats  db.Stats
+		}{
+			Title:  "Statistics",
+			Battle: *battle,
+			Stats:  battle.Stats(votes),
+		}
+
+		w.WriteHeader(http.StatusOK)
+
+		if err := tmpl.Execute(w, &templateData); err != nil {
+			slog.Info("error", "err", err)
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/some-programs/battlr/assets"
+	"github.com/some-programs/battlr/pkg/db"
+	"github.com/some-programs/battlr/pkg/scanner"
+)
+
+// uploadFormOverhead is allowed on top of MaxUploadSize for the other fields
+// of the upload form.
+const uploadFormOverhead = 1 << 20
+
+func (s *Server) SubmitForm() AppHandler {
+	tmpl, err := template.New("base.html").
+		Funcs(template.FuncMap{
+			"static":   assets.StaticHashFS.HashName,
+			"markdown": renderMarkdown,
+		},
+		).
+		ParseFS(assets.TemplateFS, "template/base.html", "template/battle-meta.html", "template/battle-submit.html")
+	if err != nil {
+		slog.Error("failed to parse clients template",
+			"err", err,
+		)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+		name := r.PathValue("name")
+		battle, err := s.DB.GetBattle(name)
+		if err != nil {
+			return err
+		}
+		if battle == nil || battle.Phase == db.PhaseHidden {
+			return db.NotFound
+		}
+		if !battle.AcceptsSubmissions(time.Now()) {
+			return s.ErrorPage(ctx, w, r, "the battle is not accepting entries", "/battles/")
+		}
+
+		clientID := getClientID(ctx)
+		if clientID == "" {
+			return errors.New("no client id found")
+		}
+		uploaderID, err := s.ownerID(ctx, *battle)
+		if err != nil {
+			return err
+		}
+		var own *db.Entry
+		if entry, ok := battle.GetEntryByUploader(uploaderID); ok {
+			own = &entry
+		}
+
+		templateData := struct {
+			Title       string
+			Battle      db.Battle
+			Entry       *db.Entry
+			Extensions  string
+			MaxUploadMB int64
+		}{
+			Title:       "Submit entry",
+			Battle:      *battle,
+			Entry:       own,
+			Extensions:  strings.Join(scanner.EntryExtensions, ","),
+			MaxUploadMB: s.MaxUploadSize >> 20,
+		}
+
+		w.WriteHeader(http.StatusOK)
+
+		if err := tmpl.Execute(w, &templateData); err != nil {
+			slog.Info("error", "err", err)
+			return err
+		}
+		return nil
+	}
+}
+
+// Upload stores an entry uploaded with a multipart form containing the
+// fields author, title and file. An entrant uploading again replaces their
+// previous entry.
+func (s *Server) Upload() AppHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+		name := r.PathValue("name")
+
+		clientID := getClientID(ctx)
+		if clientID == "" {
+			return errors.New("no client id found")
+		}
+
+		battle, err := s.DB.GetBattle(name)
+		if err != nil {
+			return err
+		}
+		if battle == nil || battle.Phase == db.PhaseHidden {
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		}
+		if !battle.AcceptsSubmissions(time.Now()) {
+			WriteJSONResponse(ctx, w, http.StatusForbidden, inspectError(db.SubmissionClosed))
+			return nil
+		}
+		uploaderID, err := s.ownerID(ctx, *battle)
+		if err != nil {
+			return err
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, s.MaxUploadSize+uploadFormOverhead)
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				WriteJSONResponse(ctx, w, http.StatusRequestEntityTooLarge, inspectError(errUploadTooLarge))
+				return nil
+			}
+			WriteJSONResponse(ctx, w, http.StatusBadRequest, inspectError(err))
+			return nil
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		author := strings.TrimSpace(r.FormValue("author"))
+		title := strings.TrimSpace(r.FormValue("title"))
+		if author == "" || title == "" {
+			WriteJSONResponse(ctx, w, http.StatusBadRequest, inspectError(errors.New("author and title are required")))
+			return nil
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			WriteJSONResponse(ctx, w, http.StatusBadRequest, inspectError(err))
+			return nil
+		}
+		defer file.Close()
+		if header.Size > s.MaxUploadSize {
+			WriteJSONResponse(ctx, w, http.StatusRequestEntityTooLarge, inspectError(errUploadTooLarge))
+			return nil
+		}
+		if !scanner.IsEntryFile(header.Filename) {
+			WriteJSONResponse(ctx, w, http.StatusBadRequest, inspectError(errUploadFormat))
+			return nil
+		}
+
+		entry, err := s.storeUpload(battle.Name, db.Entry{
+			Author:     author,
+			Title:      title,
+			Filename:   uploadFilename(author, title, filepath.Ext(header.Filename)),
+			UploaderID: uploaderID,
+		}, file)
+		switch {
+		case errors.Is(err, db.NotFound):
+			w.WriteHeader(http.StatusNotFound)
+			return nil
+		case errors.Is(err, db.SubmissionClosed):
+			WriteJSONResponse(ctx, w, http.StatusForbidden, inspectError(err))
+			return nil
+		case errors.Is(err, db.DuplicateEntry):
+			WriteJSONResponse(ctx, w, http.StatusConflict, inspectError(err))
+			return nil
+		case err != nil:
+			return err
+		}
+		WriteJSONResponse(ctx, w, http.StatusOK, entry)
+		return nil
+	}
+}
+
+var (
+	errUploadTooLarge = errors.New("file is too large")
+	errUploadFormat   = errors.New("unsupported file format, accepted: " + strings.Join(scanner.EntryExtensions, ", "))
+)
+
+// storeUpload writes an uploaded file into the battle directory and adds the
+// entry to the battle. Scans are held off until both are done so that a scan
+// never sees the entry without its file.
+func (s *Server) storeUpload(battleName string, entry db.Entry, r io.Reader) (db.Entry, error) {
+	dir := filepath.Join(s.BattlesDir, battleName)
+	// the temporary file does not have an entry extension so it is ignored
+	// by scans.
+	tmp, err := os.CreateTemp(dir, ".upload-*.part")
+	if err != nil {
+		return entry, err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return entry, err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+		tmp.Close()
+		return entry, err
+	}
+	if err := tmp.Close(); err != nil {
+		return entry, err
+	}
+	entry.Hash = hex.EncodeToString(h.Sum(nil))
+
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+
+	// the file is moved in place before the entry is committed so that a
+	// failed move leaves no entry without a file.
+	entry, replaced, err := s.DB.SubmitEntry(battleName, entry, func(entry db.Entry) error {
+		return os.Rename(tmp.Name(), filepath.Join(dir, entry.Filename))
+	})
+	if err != nil {
+		return entry, err
+	}
+	if replaced != nil && replaced.Filename != entry.Filename {
+		if err := os.Remove(filepath.Join(dir, replaced.Filename)); err != nil {
+			slog.Warn("could not remove replaced upload", "battle", battleName, "filename", replaced.Filename, "err", err)
+		}
+	}
+	slog.Info("entry uploaded", "battle", battleName, "entry", entry.ID, "filename", entry.Filename, "replaced", replaced != nil)
+	return entry, nil
+}
+
+// uploadFilename builds a filename the scanner splits back into author and
+// title.
+func uploadFilename(author, title, ext string) string {
+	clean := func(s string) string {
+		s = strings.Map(func(r rune) rune {
+			switch {
+			case r < ' ', r == '/', r == '\\', r == ':':
+				return '_'
+			}
+			return r
+		}, s)
+		// " - " separates author from title
+		s = strings.ReplaceAll(s, " - ", " _ ")
+		return strings.TrimLeft(strings.TrimSpace(s), ".")
+	}
+	return clean(author) + " - " + clean(title) + strings.ToLower(ext)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"time"
+)
+
+// DirWatcher polls a directory tree for added, removed, renamed or modified
+// files and calls OnChange once the tree has stopped changing for the
+// Debounce duration. The delay keeps half copied uploads from being scanned.
+type DirWatcher struct {
+	Fsys     fs.FS
+	Interval time.Duration
+	Debounce time.Duration
+	OnChange func() error
+}
+
+type fileState struct {
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+func (w *DirWatcher) Run(ctx context.Context) error {
+	last, err := w.snapshot()
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	var (
+		pending    bool
+		lastChange time.Time
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			current, err := w.snapshot()
+			if err != nil {
+				slog.Warn("watch: snapshot", "err", err)
+				continue
+			}
+			if !maps.Equal(last, current) {
+				last = current
+				lastChange = now
+				pending = true
+				continue
+			}
+			if pending && now.Sub(lastChange) >= w.Debounce {
+				pending = false
+				slog.Info("watch: battles directory changed, scanning")
+				if err := w.OnChange(); err != nil {
+					slog.Error("watch: scan", "err", err)
+				}
+			}
+		}
+	}
+}
+
+func (w *DirWatcher) snapshot() (map[string]fileState, error) {
+	res := make(map[string]fileState)
+	err := fs.WalkDir(w.Fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		res[name] = fileState{
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			IsDir:   d.IsDir(),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}